@@ -1,14 +1,21 @@
 package build
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/Shyp/go-circle"
 	"github.com/Shyp/go-git"
 )
 
-// GetBuilds gets the status of the 5 most recent Circle builds for a branch
-func GetBuilds(branch string) error {
+// GetBuilds gets the status of the limit most recent Circle builds for a
+// branch
+func GetBuilds(branch string, limit int) error {
 	// This throws if the branch doesn't exist
 	if _, err := git.Tip(branch); err != nil {
 		return err
@@ -21,42 +28,126 @@ func GetBuilds(branch string) error {
 		return err
 	}
 
-	cr, err := circle.GetTree(remote.Path, remote.RepoName, branch)
-	if err != nil {
+	if err := printBuilds(os.Stdout, circle.DefaultClient, remote.Path, remote.RepoName, branch, limit); err != nil {
 		return err
 	}
 
-	buildCount := 0
+	fmt.Println("\nMost recent build statuses fetched!")
+
+	return nil
+}
 
-	if len(*cr) < 5 {
-		buildCount = len(*cr)
-	} else {
-		buildCount = 5 // Limited to 5 most recent builds.
+// printBuilds writes the URL, status and compare URL of the limit most recent
+// builds on branch to w.
+func printBuilds(w io.Writer, client *circle.Client, org, repoName, branch string, limit int) error {
+	cr, err := client.GetTreePage(context.Background(), org, repoName, branch, &circle.TreeOptions{Limit: limit})
+	if err != nil {
+		return err
 	}
 
-	for i := 0; i < buildCount; i++ {
-		build := (*cr)[i]
-		ghUrl, url, status := build.CompareURL, build.BuildURL, build.Status
-
-		// Based on the status of the build, change the color of status print out
-		if build.Passed() {
-			status = fmt.Sprintf("\033[38;05;119m%-8s\033[0m", status)
-		} else if build.NotRunning() {
-			status = fmt.Sprintf("\033[38;05;20m%-8s\033[0m", status)
-		} else if build.Failed() {
-			status = fmt.Sprintf("\033[38;05;160m%-8s\033[0m", status)
-		} else if build.Running() {
-			status = fmt.Sprintf("\033[38;05;80m%-8s\033[0m", status)
-		} else {
-			status = fmt.Sprintf("\033[38;05;0m%-8s\033[0m", status)
-		}
+	for _, build := range *cr {
+		fmt.Fprintln(w, build.BuildURL, colorStatus(build.Status), build.CompareURL)
+	}
+	return nil
+}
+
+// colorStatus returns status, padded and colored based on whether the build
+// passed, failed, or is still running.
+func colorStatus(status circle.Status) string {
+	if status.IsSuccess() {
+		return fmt.Sprintf("\033[38;05;119m%-8s\033[0m", status)
+	} else if status.IsPending() {
+		return fmt.Sprintf("\033[38;05;20m%-8s\033[0m", status)
+	} else if status.IsFailure() {
+		return fmt.Sprintf("\033[38;05;160m%-8s\033[0m", status)
+	} else if status == circle.StatusRunning {
+		return fmt.Sprintf("\033[38;05;80m%-8s\033[0m", status)
+	}
+	return fmt.Sprintf("\033[38;05;0m%-8s\033[0m", status)
+}
 
-		fmt.Println(url, status, ghUrl)
+// GetRecentBuilds prints the most recent builds for every project followed by
+// the owner of org's token.
+func GetRecentBuilds(ctx context.Context, org string, limit int) error {
+	return printRecent(ctx, os.Stdout, circle.DefaultClient, org, limit)
+}
 
+// printRecent writes the project, branch, URL and status of the limit most
+// recent builds across every followed project to w.
+func printRecent(ctx context.Context, w io.Writer, client *circle.Client, org string, limit int) error {
+	cr, err := client.GetRecentBuilds(ctx, org, &circle.TreeOptions{Limit: limit})
+	if err != nil {
+		return err
+	}
+	if len(*cr) == 0 {
+		fmt.Fprintln(w, "No recent builds")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, build := range *cr {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", build.Project(), build.Branch, build.BuildURL, colorStatus(build.Status))
 	}
+	return tw.Flush()
+}
 
-	fmt.Println("\nMost recent build statuses fetched!")
+// GetProjects prints every project followed by the owner of org's token, with
+// the latest build on each branch. If filterOrg is not empty, only projects
+// owned by filterOrg are printed.
+func GetProjects(ctx context.Context, org string, filterOrg string) error {
+	return printProjects(ctx, os.Stdout, circle.DefaultClient, org, filterOrg)
+}
 
+func printProjects(ctx context.Context, w io.Writer, client *circle.Client, org string, filterOrg string) error {
+	projects, err := client.ListProjects(ctx, org)
+	if err != nil {
+		return err
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return strings.ToLower(projects[i].Name()) < strings.ToLower(projects[j].Name())
+	})
+	printed := 0
+	for _, p := range projects {
+		if filterOrg != "" && !strings.EqualFold(p.Username, filterOrg) {
+			continue
+		}
+		printed++
+		fmt.Fprintln(w, p.Name())
+		branches := make([]string, 0, len(p.Branches))
+		for name := range p.Branches {
+			branches = append(branches, name)
+		}
+		// The default branch goes first, then the rest in order.
+		sort.Slice(branches, func(i, j int) bool {
+			if branches[i] == p.DefaultBranch || branches[j] == p.DefaultBranch {
+				return branches[i] == p.DefaultBranch
+			}
+			return branches[i] < branches[j]
+		})
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, name := range branches {
+			summary := p.Branches[name]
+			last := summary.LastBuild()
+			if last == nil {
+				fmt.Fprintf(tw, "  %s\t\tno builds\n", name)
+				continue
+			}
+			status := colorStatus(last.Status)
+			if len(summary.RunningBuilds) > 1 {
+				status += fmt.Sprintf(" (%d running)", len(summary.RunningBuilds))
+			}
+			fmt.Fprintf(tw, "  %s\t#%d\t%s\n", name, last.BuildNum, status)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if printed == 0 {
+		if filterOrg != "" {
+			fmt.Fprintf(w, "You don't follow any projects owned by %s\n", filterOrg)
+		} else {
+			fmt.Fprintln(w, "You don't follow any projects")
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,97 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shyp/go-circle"
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func TestPrintBuilds(t *testing.T) {
+	c := &circle.Client{
+		Tokens:     circle.StaticToken("token"),
+		HTTPClient: circletest.NewReplayClient("../testdata/fixtures"),
+		Retry:      circle.NoRetries,
+	}
+	var buf bytes.Buffer
+	if err := printBuilds(&buf, c, "Shyp", "go-circle", "master", 5); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 builds, got %d:\n%s", len(lines), buf.String())
+	}
+	want := []struct {
+		url    string
+		status string
+	}{
+		{"https://circleci.com/gh/Shyp/go-circle/1290", "\033[38;05;80mrunning "},
+		{"https://circleci.com/gh/Shyp/go-circle/1289", "\033[38;05;20mqueued  "},
+		{"https://circleci.com/gh/Shyp/go-circle/1288", "\033[38;05;20mscheduled"},
+		{"https://circleci.com/gh/Shyp/go-circle/1287", "\033[38;05;20mnot_running"},
+		{"https://circleci.com/gh/Shyp/go-circle/1286", "\033[38;05;160minfrastructure_fail"},
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(lines[i], w.url+" "+w.status) {
+			t.Errorf("line %d: got %q, want prefix %q", i, lines[i], w.url+" "+w.status)
+		}
+	}
+}
+
+func TestPrintRecent(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "success", QueuedAt: start})
+	s.AddBuild("Shyp", "api", &circletest.Build{Branch: "deploy", Status: "failed", QueuedAt: start.Add(time.Minute)})
+	c := &circle.Client{BaseURL: s.URL, Tokens: circle.StaticToken("token"), Retry: circle.NoRetries}
+	var buf bytes.Buffer
+	if err := printRecent(context.Background(), &buf, c, "Shyp", 10); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 builds, got %d:\n%s", len(lines), buf.String())
+	}
+	want := []struct {
+		prefix string
+		status string
+	}{
+		{"Shyp/api        deploy  " + s.URL + "/gh/Shyp/api/1", "\033[38;05;160mfailed  "},
+		{"Shyp/go-circle  master  " + s.URL + "/gh/Shyp/go-circle/1", "\033[38;05;119msuccess "},
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(lines[i], w.prefix) || !strings.Contains(lines[i], w.status) {
+			t.Errorf("line %d: got %q, want prefix %q and status %q", i, lines[i], w.prefix, w.status)
+		}
+	}
+}
+
+func TestPrintProjects(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "success"})
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "deploy", Status: "failed"})
+	s.AddBuild("kevinburke", "rest", &circletest.Build{Branch: "master", Status: "success"})
+	c := &circle.Client{BaseURL: s.URL, Tokens: circle.StaticToken("token"), Retry: circle.NoRetries}
+	ctx := context.Background()
+	for _, repo := range [][2]string{{"Shyp", "go-circle"}, {"kevinburke", "rest"}} {
+		if _, err := c.Follow(ctx, "github", repo[0], repo[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := printProjects(ctx, &buf, c, "Shyp", "shyp"); err != nil {
+		t.Fatal(err)
+	}
+	want := "Shyp/go-circle\n" +
+		"  master  #1  \033[38;05;119msuccess \033[0m\n" +
+		"  deploy  #2  \033[38;05;160mfailed  \033[0m\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%q\nwant:\n%q", buf.String(), want)
+	}
+}
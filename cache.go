@@ -0,0 +1,216 @@
+package circle
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCacheTTL is how long a cached response is used without asking
+// CircleCI whether it has changed.
+const DefaultCacheTTL = 5 * time.Second
+
+// DiskCache stores GET responses on disk, so that several processes polling
+// the same branch share responses. Entries with an ETag or Last-Modified
+// header are revalidated with a conditional request once they are older than
+// TTL; entries without one are refetched.
+//
+// A DiskCache is safe for use by multiple goroutines and processes.
+type DiskCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// NewDiskCache returns a DiskCache in the "circle" directory under the user's
+// cache directory, which is $XDG_CACHE_HOME or ~/.cache on Linux. It returns
+// an error on platforms where the cache can't lock its files, which are the
+// platforms other than Windows and the BSDs, Linux and macOS.
+func NewDiskCache() (*DiskCache, error) {
+	if errCacheUnsupported != nil {
+		return nil, errCacheUnsupported
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &DiskCache{Dir: filepath.Join(dir, "circle"), TTL: DefaultCacheTTL}, nil
+}
+
+type cacheEntry struct {
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+	FetchedAt    time.Time       `json:"fetched_at"`
+	Body         json.RawMessage `json:"body"`
+}
+
+func (d *DiskCache) ttl() time.Duration {
+	if d.TTL == 0 {
+		return DefaultCacheTTL
+	}
+	return d.TTL
+}
+
+// path returns the file for the given key. Keys include the API token's
+// organization, so a response is never served to a different token.
+func (d *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// lock takes a lock on the cache's lock file, and returns a function that
+// releases it. Every entry shares one lock file, so there is only ever one
+// file in the cache directory that isn't an entry.
+func (d *DiskCache) lock(exclusive bool) (func(), error) {
+	if err := os.MkdirAll(d.Dir, 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(d.Dir, "lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+func (d *DiskCache) load(key string) (*cacheEntry, error) {
+	unlock, err := d.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	data, err := ioutil.ReadFile(d.path(key))
+	if err != nil {
+		return nil, err
+	}
+	e := new(cacheEntry)
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (d *DiskCache) store(key string, e *cacheEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	unlock, err := d.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	tmp, err := ioutil.TempFile(d.Dir, "entry")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), d.path(key))
+}
+
+// Clear removes every entry from the cache. The lock file stays, so that
+// processes using the cache at the same time keep locking the same file.
+func (d *DiskCache) Clear() error {
+	if _, err := os.Stat(d.Dir); os.IsNotExist(err) {
+		return nil
+	}
+	// Wait for writers to finish, so they don't store an entry after we
+	// have looked at the directory.
+	unlock, err := d.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	files, err := ioutil.ReadDir(d.Dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.Name() == "lock" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(d.Dir, f.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// cachedGet is like get, but serves the response from c.Cache when it can.
+// Cache failures are not fatal; the request is made as usual.
+func (c *Client) cachedGet(ctx context.Context, org, path string, v interface{}) error {
+	req, err := c.newRequest(ctx, org, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+	key := org + " " + req.URL.String()
+	entry, _ := c.Cache.load(key)
+	if entry != nil {
+		if time.Since(entry.FetchedAt) < c.Cache.ttl() {
+			return json.Unmarshal(entry.Body, v)
+		}
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		io.Copy(ioutil.Discard, resp.Body)
+		if entry != nil {
+			entry.FetchedAt = time.Now()
+			c.Cache.store(key, entry)
+			return json.Unmarshal(entry.Body, v)
+		}
+		// There's no entry to serve, so ask again, without any validators.
+		req, err := c.newRequest(ctx, org, "GET", path, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Cache-Control", "no-cache")
+		return c.do(req, v)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return c.secrets.redactError(err)
+	}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(v); err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusOK && json.Valid(body) {
+		c.Cache.store(key, &cacheEntry{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+			Body:         body,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package circle
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T, ttl time.Duration) *DiskCache {
+	if errCacheUnsupported != nil {
+		t.Skip(errCacheUnsupported)
+	}
+	dir, err := ioutil.TempDir("", "circle-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &DiskCache{Dir: dir, TTL: ttl}
+}
+
+func TestCacheTTL(t *testing.T) {
+	var count int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.Write([]byte(`[{"build_num": 3}]`))
+	}))
+	defer s.Close()
+	cache := newTestCache(t, time.Minute)
+	defer os.RemoveAll(cache.Dir)
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Cache: cache}
+	// A second client, like a second "circle wait" process.
+	c2 := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Cache: cache}
+	for _, client := range []*Client{c, c, c2} {
+		cr, err := client.GetTree(context.Background(), "Shyp", "go-circle", "master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(*cr) != 1 || (*cr)[0].BuildNum != 3 {
+			t.Fatalf("bad response: %#v", cr)
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected 1 request, got %d", count)
+	}
+	// Different org, different entry.
+	if _, err := c.GetTree(context.Background(), "other", "go-circle", "master"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 requests, got %d", count)
+	}
+}
+
+func TestCacheETag(t *testing.T) {
+	var count, notModified int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"build_num": 9}`))
+	}))
+	defer s.Close()
+	cache := newTestCache(t, time.Nanosecond)
+	defer os.RemoveAll(cache.Dir)
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Cache: cache}
+	for i := 0; i < 3; i++ {
+		cb, err := c.GetBuild(context.Background(), "Shyp", "go-circle", 9)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cb.BuildNum != 9 {
+			t.Fatalf("bad build: %#v", cb)
+		}
+	}
+	if count != 3 || notModified != 2 {
+		t.Errorf("expected 3 requests with 2 conditional hits, got %d and %d", count, notModified)
+	}
+}
+
+func TestCacheNotModifiedWithoutEntry(t *testing.T) {
+	var count int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		// Like a proxy that answers 304 for a response the cache doesn't
+		// have.
+		if r.Header.Get("Cache-Control") != "no-cache" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"build_num": 9}`))
+	}))
+	defer s.Close()
+	cache := newTestCache(t, time.Minute)
+	defer os.RemoveAll(cache.Dir)
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Cache: cache}
+	cb, err := c.GetBuild(context.Background(), "Shyp", "go-circle", 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.BuildNum != 9 || count != 2 {
+		t.Errorf("expected build 9 after 2 requests, got %d after %d", cb.BuildNum, count)
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"build_num": 3}]`))
+	}))
+	defer s.Close()
+	cache := newTestCache(t, time.Nanosecond)
+	defer os.RemoveAll(cache.Dir)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Cache: cache}
+			cr, err := c.GetTree(context.Background(), "Shyp", "go-circle", "master")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(*cr) != 1 {
+				t.Errorf("bad response: %#v", cr)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// checkCleared fails the test unless the lock file is the only file in dir.
+func checkCleared(t *testing.T, dir string) {
+	t.Helper()
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "lock" {
+		names := make([]string, len(files))
+		for i, f := range files {
+			names[i] = f.Name()
+		}
+		t.Errorf("expected only the lock file in the cache, got %v", names)
+	}
+}
+
+func TestCacheClear(t *testing.T) {
+	cache := newTestCache(t, time.Minute)
+	defer os.RemoveAll(cache.Dir)
+	if err := cache.store("key", &cacheEntry{Body: []byte("[]")}); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(cache.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if f.Name() != "lock" && filepath.Ext(f.Name()) != ".json" {
+			t.Errorf("unexpected file %s in the cache", f.Name())
+		}
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	checkCleared(t, cache.Dir)
+	if _, err := cache.load("key"); !os.IsNotExist(err) {
+		t.Errorf("expected the entry to be gone, got %v", err)
+	}
+	if err := cache.Clear(); err != nil {
+		t.Errorf("clearing an empty cache: %v", err)
+	}
+	missing := &DiskCache{Dir: filepath.Join(cache.Dir, "missing")}
+	if err := missing.Clear(); err != nil {
+		t.Errorf("clearing a cache that doesn't exist: %v", err)
+	}
+}
+
+func TestCacheClearWhileWriting(t *testing.T) {
+	cache := newTestCache(t, time.Minute)
+	defer os.RemoveAll(cache.Dir)
+	// Another process using the same directory.
+	other := &DiskCache{Dir: cache.Dir, TTL: time.Minute}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				key := fmt.Sprintf("key-%d-%d", i, j%10)
+				if err := other.store(key, &cacheEntry{Body: []byte("[]")}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 50; i++ {
+		if err := cache.Clear(); err != nil {
+			t.Error(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+	if err := cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	checkCleared(t, cache.Dir)
+}
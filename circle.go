@@ -1,71 +1,94 @@
 package circle
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/Shyp/go-types"
-	"github.com/kevinburke/rest"
-	"golang.org/x/sync/errgroup"
 )
 
-var client http.Client
-
-// TODO switch all clients to use this
-var v11client *rest.Client
-
-func init() {
-	client = http.Client{
-		Timeout: 10 * time.Second,
-	}
-	v11client = rest.NewClient("", "", v11BaseUri)
-}
-
 const VERSION = "0.27"
-const baseUri = "https://circleci.com/api/v1/project"
-const v11BaseUri = "https://circleci.com/api/v1.1/project"
 
+// Path prefixes for the two API versions we use, relative to Client.BaseURL.
+const v1Prefix = "/v1/project"
+const v11Prefix = "/v1.1/project"
+
+// TreeBuild is a build as it appears in a list of builds, like the response
+// from GetTree. It has everything a CircleBuild has except the steps.
 type TreeBuild struct {
-	BuildNum   int    `json:"build_num"`
-	BuildURL   string `json:"build_url"`
-	CompareURL string `json:"compare"`
+	AllCommitDetails   []CommitDetails `json:"all_commit_details"`
+	AuthorDate         types.NullTime  `json:"author_date"`
+	AuthorEmail        string          `json:"author_email"`
+	AuthorName         string          `json:"author_name"`
+	Body               string          `json:"body"`
+	Branch             string          `json:"branch"`
+	BuildNum           int             `json:"build_num"`
+	BuildTime          CircleDuration  `json:"build_time_millis"`
+	BuildURL           string          `json:"build_url"`
+	Canceled           bool            `json:"canceled"`
+	CommitterDate      types.NullTime  `json:"committer_date"`
+	CommitterEmail     string          `json:"committer_email"`
+	CommitterName      string          `json:"committer_name"`
+	CompareURL         string          `json:"compare"`
+	InfrastructureFail bool            `json:"infrastructure_fail"`
+	Lifecycle          Lifecycle       `json:"lifecycle"`
+	Messages           []Message       `json:"messages"`
+	Nodes              []Node          `json:"node"`
+	Outcome            Outcome         `json:"outcome"`
+	Parallel           int             `json:"parallel"`
+	Picard             *Picard         `json:"picard"`
 	// Tree builds have a `previous_successful_build` field but as far as I can
 	// tell it is always null. Instead this field is set
 	Previous      PreviousBuild  `json:"previous"`
+	PullRequests  []PullRequest  `json:"pull_requests"`
 	QueuedAt      types.NullTime `json:"queued_at"`
 	RepoName      string         `json:"reponame"`
-	Status        string         `json:"status"`
+	SSHUsers      []SSHUser      `json:"ssh_users"`
+	Status        Status         `json:"status"`
 	StartTime     types.NullTime `json:"start_time"`
 	StopTime      types.NullTime `json:"stop_time"`
+	Subject       string         `json:"subject"`
+	Timedout      bool           `json:"timedout"`
 	UsageQueuedAt types.NullTime `json:"usage_queued_at"`
+	User          *User          `json:"user"`
 	Username      string         `json:"username"`
 	VCSRevision   string         `json:"vcs_revision"`
+	VCSTag        string         `json:"vcs_tag"`
 	VCSType       string         `json:"vcs_type"`
+	VCSURL        string         `json:"vcs_url"`
+	Why           string         `json:"why"` // "github", "retry", "api", ...
+}
+
+// Project returns the organization and name of the project the build belongs
+// to, for example "Shyp/go-circle".
+func (tb TreeBuild) Project() string {
+	return tb.Username + "/" + tb.RepoName
 }
 
 func (tb TreeBuild) Passed() bool {
-	return tb.Status == "success" || tb.Status == "fixed"
+	return tb.Status.IsSuccess()
 }
 
 func (tb TreeBuild) NotRunning() bool {
-	return tb.Status == "not_running" || tb.Status == "scheduled" || tb.Status == "queued"
+	return tb.Status.IsPending()
 }
 
 func (tb TreeBuild) Running() bool {
-	return tb.Status == "running"
+	return tb.Status == StatusRunning
 }
 
 func (tb TreeBuild) Failed() bool {
-	return tb.Status == "failed" || tb.Status == "timedout" || tb.Status == "no_tests" || tb.Status == "infrastructure_fail"
+	return tb.Status.IsFailure()
 }
 
 type CircleArtifact struct {
@@ -76,15 +99,48 @@ type CircleArtifact struct {
 }
 
 type CircleBuild struct {
-	BuildNum                uint32         `json:"build_num"`
-	Parallel                uint8          `json:"parallel"`
-	PreviousSuccessfulBuild PreviousBuild  `json:"previous_successful_build"`
-	QueuedAt                types.NullTime `json:"queued_at"`
-	RepoName                string         `json:"reponame"` // "go"
-	Steps                   []Step         `json:"steps"`
-	VCSType                 string         `json:"vcs_type"` // "github", "bitbucket"
-	UsageQueuedAt           types.NullTime `json:"usage_queued_at"`
-	Username                string         `json:"username"` // "golang"
+	AllCommitDetails        []CommitDetails   `json:"all_commit_details"`
+	AuthorDate              types.NullTime    `json:"author_date"`
+	AuthorEmail             string            `json:"author_email"`
+	AuthorName              string            `json:"author_name"`
+	Body                    string            `json:"body"`
+	Branch                  string            `json:"branch"`
+	BuildNum                uint32            `json:"build_num"`
+	BuildParameters         map[string]string `json:"build_parameters"`
+	BuildTime               CircleDuration    `json:"build_time_millis"`
+	BuildURL                string            `json:"build_url"`
+	Canceled                bool              `json:"canceled"`
+	CommitterDate           types.NullTime    `json:"committer_date"`
+	CommitterEmail          string            `json:"committer_email"`
+	CommitterName           string            `json:"committer_name"`
+	CompareURL              string            `json:"compare"`
+	InfrastructureFail      bool              `json:"infrastructure_fail"`
+	Lifecycle               Lifecycle         `json:"lifecycle"`
+	Messages                []Message         `json:"messages"`
+	Nodes                   []Node            `json:"node"`
+	Outcome                 Outcome           `json:"outcome"`
+	Parallel                uint8             `json:"parallel"`
+	Picard                  *Picard           `json:"picard"`
+	Previous                PreviousBuild     `json:"previous"`
+	PreviousSuccessfulBuild PreviousBuild     `json:"previous_successful_build"`
+	PullRequests            []PullRequest     `json:"pull_requests"`
+	QueuedAt                types.NullTime    `json:"queued_at"`
+	RepoName                string            `json:"reponame"` // "go"
+	SSHUsers                []SSHUser         `json:"ssh_users"`
+	StartTime               types.NullTime    `json:"start_time"`
+	Status                  Status            `json:"status"`
+	Steps                   []Step            `json:"steps"`
+	StopTime                types.NullTime    `json:"stop_time"`
+	Subject                 string            `json:"subject"`
+	Timedout                bool              `json:"timedout"`
+	UsageQueuedAt           types.NullTime    `json:"usage_queued_at"`
+	User                    *User             `json:"user"`
+	Username                string            `json:"username"` // "golang"
+	VCSRevision             string            `json:"vcs_revision"`
+	VCSTag                  string            `json:"vcs_tag"`
+	VCSType                 string            `json:"vcs_type"` // "github", "bitbucket"
+	VCSURL                  string            `json:"vcs_url"`
+	Why                     string            `json:"why"` // "github", "retry", "api", ...
 }
 
 // Failures returns an array of (buildStep, containerID) integers identifying
@@ -109,50 +165,85 @@ type CircleOutput struct {
 
 type CircleOutputs []*CircleOutput
 
+// FailureTexts returns the output of every failed action in the build, using
+// the DefaultClient.
 func (cb *CircleBuild) FailureTexts(ctx context.Context) ([]string, error) {
-	group, errctx := errgroup.WithContext(ctx)
-	// todo this is not great design
-	token, err := getToken(cb.Username)
+	return DefaultClient.FailureTexts(ctx, cb)
+}
+
+// FailureTexts returns the output of every failed action in the build. The
+// results are in the same order as cb.Failures().
+func (c *Client) FailureTexts(ctx context.Context, cb *CircleBuild) ([]string, error) {
+	failures := cb.Failures()
+	results := make([]string, len(failures))
+	err := c.forEach(ctx, len(failures), func(ctx context.Context, i int) error {
+		message, err := c.ActionOutput(ctx, cb, failures[i][0], failures[i][1])
+		results[i] = message
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	failures := cb.Failures()
-	results := make([]string, len(failures))
-	for i, failure := range failures {
-		failure := failure
-		i := i
-		group.Go(func() error {
-			// URL we are trying to fetch looks like:
-			// https://circleci.com/api/v1.1/project/github/Shyp/go-circle/11/output/9/0
-			uri := fmt.Sprintf("/%s/%s/%s/%d/output/%d/%d?circle-token=%s", cb.VCSType, cb.Username, cb.RepoName, cb.BuildNum, failure[0], failure[1], token)
-			req, err := v11client.NewRequest("GET", uri, nil)
-			if err != nil {
-				return err
-			}
-			req = req.WithContext(errctx)
-			var outputs []*CircleOutput
-			if err := v11client.Do(req, &outputs); err != nil {
-				return err
-			}
-			var message string
-			for i := range outputs {
-				message = message + outputs[i].Message + "\n"
-			}
-			results[i] = message
-			return nil
-		})
+	return results, nil
+}
+
+// ActionOutput returns the output of the action that ran step on the
+// container with the given index.
+func (c *Client) ActionOutput(ctx context.Context, cb *CircleBuild, step, index int) (string, error) {
+	// URL we are trying to fetch looks like:
+	// https://circleci.com/api/v1.1/project/github/Shyp/go-circle/11/output/9/0
+	uri := fmt.Sprintf("%s/%s/%s/%s/%d/output/%d/%d", v11Prefix, cb.VCSType, cb.Username, cb.RepoName, cb.BuildNum, step, index)
+	var outputs []*CircleOutput
+	if err := c.get(ctx, cb.Username, uri, &outputs); err != nil {
+		return "", err
+	}
+	var message string
+	for i := range outputs {
+		message = message + outputs[i].Message + "\n"
+	}
+	return message, nil
+}
+
+// sshCommandRx matches the ssh command in the output of the step that enables
+// SSH. CircleCI 1.0 printed "ssh -p PORT ubuntu@HOST"; 2.0 leaves out the user.
+var sshCommandRx = regexp.MustCompile(`ssh -p \d+ (?:[\w.-]+@)?[\w.:-]+`)
+
+// SSHCommands returns the ssh command line for each container of a build that
+// was started with SSH enabled, by reading the output of the step that enables
+// SSH. It returns an empty slice if that step hasn't printed the details yet.
+func (c *Client) SSHCommands(ctx context.Context, cb *CircleBuild) ([]string, error) {
+	var actions [][2]int
+	for i, step := range cb.Steps {
+		if !strings.Contains(strings.ToLower(step.Name), "ssh") {
+			continue
+		}
+		for j := range step.Actions {
+			actions = append(actions, [2]int{i, j})
+		}
 	}
-	if err := group.Wait(); err != nil {
+	results := make([]string, len(actions))
+	err := c.forEach(ctx, len(actions), func(ctx context.Context, i int) error {
+		output, err := c.ActionOutput(ctx, cb, actions[i][0], actions[i][1])
+		results[i] = sshCommandRx.FindString(output)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
-	return results, nil
+	commands := make([]string, 0, len(results))
+	for _, cmd := range results {
+		if cmd != "" {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands, nil
 }
 
 type PreviousBuild struct {
 	BuildNum int `json:"build_num"`
 	// would be neat to make this a time.Duration, easier to use the passed in
 	// value.
-	Status string `json:"status"`
+	Status Status `json:"status"`
 
 	BuildDurationMs int `json:"build_time_millis"`
 }
@@ -163,175 +254,171 @@ type Step struct {
 }
 
 type Action struct {
-	Name      string         `json:"name"`
-	OutputURL URL            `json:"output_url"`
-	Runtime   CircleDuration `json:"run_time_millis"`
-	Status    string         `json:"status"`
+	BashCommand        string         `json:"bash_command"`
+	Canceled           bool           `json:"canceled"`
+	EndTime            types.NullTime `json:"end_time"`
+	ExitCode           *int           `json:"exit_code"` // nil if the action didn't run a command
+	HasOutput          bool           `json:"has_output"`
+	Index              int            `json:"index"` // the container the action ran on
+	InfrastructureFail bool           `json:"infrastructure_fail"`
+	Messages           []Message      `json:"messages"`
+	Name               string         `json:"name"`
+	OutputURL          URL            `json:"output_url"`
+	Parallel           bool           `json:"parallel"`
+	Runtime            CircleDuration `json:"run_time_millis"`
+	StartTime          types.NullTime `json:"start_time"`
+	Status             Status         `json:"status"`
+	Step               int            `json:"step"`
+	Timedout           bool           `json:"timedout"`
+	Type               string         `json:"type"` // "infrastructure", "test", ...
 }
 
 func (a *Action) Failed() bool {
-	return a.Status == "failed" || a.Status == "timedout"
+	return a.Status.IsFailure()
 }
 
-func getTreeUri(org string, project string, branch string, token string) string {
-	return fmt.Sprintf("/%s/%s/tree/%s?circle-token=%s", org, project, branch, token)
+func getTreeUri(org string, project string, branch string) string {
+	return fmt.Sprintf("%s/%s/%s/tree/%s", v1Prefix, org, project, url.PathEscape(branch))
 }
 
-func getBuildUri(org string, project string, build int, token string) string {
-	return fmt.Sprintf("/%s/%s/%d?circle-token=%s", org, project, build, token)
+func getBuildUri(org string, project string, build int) string {
+	return fmt.Sprintf("%s/%s/%s/%d", v1Prefix, org, project, build)
 }
 
-func getCancelUri(org string, project string, build int, token string) string {
-	return fmt.Sprintf("%s/%s/%s/%d/cancel?circle-token=%s", baseUri, org, project, build, token)
+func getCancelUri(org string, project string, build int) string {
+	return fmt.Sprintf("%s/%s/%s/%d/cancel", v1Prefix, org, project, build)
 }
 
-func getArtifactsUri(org string, project string, build int, token string) string {
-	return fmt.Sprintf("%s/%s/%s/%d/artifacts?circle-token=%s", baseUri, org, project, build, token)
+func getArtifactsUri(org string, project string, build int) string {
+	return fmt.Sprintf("%s/%s/%s/%d/artifacts", v1Prefix, org, project, build)
 }
 
 type CircleTreeResponse []TreeBuild
 
-func makeRequest(method, uri string) (io.ReadCloser, error) {
-	req, err := http.NewRequest(method, uri, nil)
+// Enable follows the project on CircleCI, which turns on builds for it.
+func (c *Client) Enable(ctx context.Context, host string, org string, repoName string) error {
+	vcs, err := VCSType(host)
 	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("User-Agent", fmt.Sprintf("circle-command-line-client/%s", VERSION))
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode >= 400 {
-		// TODO handle build not found, you get back {"message": "Build not found"}
-		return nil, fmt.Errorf("Request failed with status [%d]", resp.StatusCode)
+		return fmt.Errorf("can't enable: %v", err)
 	}
+	_, err = c.Follow(ctx, vcs, org, repoName)
+	return err
+}
 
-	return resp.Body, nil
+// Rebuild retries the given build.
+func (c *Client) Rebuild(ctx context.Context, tb *TreeBuild) error {
+	_, err := c.RetryBuild(ctx, tb.VCSType, tb.Username, tb.RepoName, tb.BuildNum, nil)
+	return err
 }
 
-type FollowResponse struct {
-	Following bool `json:"following"`
-	// TODO...
+// RebuildOptions configure how RetryBuild reruns a build.
+type RebuildOptions struct {
+	// NoCache rebuilds without restoring the dependency cache.
+	NoCache bool `json:"no_cache,omitempty"`
+	// SSH rebuilds with SSH enabled, so you can log in to the build
+	// containers. Use SSHCommands to find out how.
+	SSH bool `json:"-"`
 }
 
-func Enable(ctx context.Context, host string, org string, repoName string) error {
-	token, err := getToken(org)
-	if err != nil {
-		return err
-	}
-	var vcs string
-	switch {
-	case strings.Contains(host, "github.com"):
-		vcs = "github"
-	case strings.Contains(host, "bitbucket.org"):
-		vcs = "bitbucket"
-	default:
-		return fmt.Errorf("can't enable unknown host %s", host)
+// RetryBuild reruns the build with the given number and returns the new build.
+// vcsType is "github" or "bitbucket"; opts may be nil.
+func (c *Client) RetryBuild(ctx context.Context, vcsType, org, project string, buildNum int, opts *RebuildOptions) (*CircleBuild, error) {
+	// https://circleci.com/gh/segmentio/db-service/1488
+	// url we have is https://circleci.com/api/v1.1/project/github/segmentio/db-service/1486/retry
+	if opts == nil {
+		opts = new(RebuildOptions)
 	}
-	uri := fmt.Sprintf("/%s/%s/%s/follow?circle-token=%s", vcs, org, repoName, token)
-	req, err := v11client.NewRequest("POST", uri, nil)
-	if err != nil {
-		return err
+	action := "retry"
+	if opts.SSH {
+		action = "ssh"
 	}
-	req = req.WithContext(ctx)
-	fr := new(FollowResponse)
-	if err := v11client.Do(req, fr); err != nil {
-		return err
+	uri := fmt.Sprintf("%s/%d/%s", getProjectUri(vcsType, org, project), buildNum, action)
+	body := []byte("null")
+	if opts.NoCache {
+		var err error
+		body, err = json.Marshal(opts)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if !fr.Following {
-		return errors.New("not following the project")
+	cb := new(CircleBuild)
+	if err := c.post(ctx, org, uri, bytes.NewReader(body), cb); err != nil {
+		return nil, err
 	}
-	return nil
+	return cb, nil
 }
 
-func Rebuild(ctx context.Context, tb *TreeBuild) error {
-	token, err := getToken(tb.Username)
-	if err != nil {
-		return err
-	}
-	// https://circleci.com/gh/segmentio/db-service/1488
-	// url we have is https://circleci.com/api/v1.1/project/github/segmentio/db-service/1486/retry
-	uri := fmt.Sprintf("/%s/%s/%s/%d/retry?circle-token=%s", tb.VCSType, tb.Username, tb.RepoName, tb.BuildNum, token)
-	req, err := v11client.NewRequest("POST", uri, strings.NewReader("null"))
-	if err != nil {
-		return err
-	}
-	req = req.WithContext(ctx)
-	return v11client.Do(req, nil)
+// ClearCache deletes the project's dependency and source caches on CircleCI,
+// so the next build starts without them. It's the same as the "Clear cache"
+// button in the project settings.
+func (c *Client) ClearCache(ctx context.Context, vcsType, org, project string) error {
+	return c.delete(ctx, org, getProjectUri(vcsType, org, project)+"/build-cache", nil)
 }
 
-func GetTree(org string, project string, branch string) (*CircleTreeResponse, error) {
-	return GetTreeContext(context.Background(), org, project, branch)
+// TriggerOptions configure a new build started with TriggerBuild. The zero
+// value builds the tip of the branch with the project's usual settings.
+type TriggerOptions struct {
+	// Revision is the commit to build. It must be on the branch.
+	Revision string `json:"revision,omitempty"`
+	// Tag is the git tag to build, instead of a branch.
+	Tag string `json:"tag,omitempty"`
+	// Parallel is the number of containers to use, overriding the project
+	// setting.
+	Parallel int `json:"parallel,omitempty"`
+	// BuildParameters are set as environment variables in the build, for
+	// example "RUN_EXTENDED_TESTS": "true".
+	BuildParameters map[string]string `json:"build_parameters,omitempty"`
 }
 
-func GetTreeContext(ctx context.Context, org, project, branch string) (*CircleTreeResponse, error) {
-	token, err := getToken(org)
-	if err != nil {
-		return nil, err
+// TriggerBuild starts a new build on branch and returns it. If branch is
+// empty, opts.Tag (or the project's default branch, if there's no tag) is
+// built instead. vcsType is "github" or "bitbucket"; opts may be nil.
+func (c *Client) TriggerBuild(ctx context.Context, vcsType, org, project, branch string, opts *TriggerOptions) (*CircleBuild, error) {
+	uri := getProjectUri(vcsType, org, project)
+	if branch != "" {
+		uri += "/tree/" + url.PathEscape(branch)
+	}
+	if opts == nil {
+		opts = new(TriggerOptions)
 	}
-	uri := getTreeUri(org, project, branch, token)
-	client := rest.NewClient("", "", baseUri)
-	req, err := client.NewRequest("GET", uri, nil)
+	body, err := json.Marshal(opts)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-	cr := new(CircleTreeResponse)
-	if err := client.Do(req, cr); err != nil {
+	cb := new(CircleBuild)
+	if err := c.post(ctx, org, uri, bytes.NewReader(body), cb); err != nil {
 		return nil, err
 	}
-	return cr, nil
+	return cb, nil
 }
 
-func GetBuild(org string, project string, buildNum int) (*CircleBuild, error) {
-	token, err := getToken(org)
-	if err != nil {
-		return nil, err
-	}
-	uri := getBuildUri(org, project, buildNum, token)
-	client := rest.NewClient("", "", baseUri)
-	req, err := client.NewRequest("GET", uri, nil)
-	if err != nil {
-		return nil, err
-	}
+// GetTree returns the most recent builds for the given branch. Use
+// GetTreePage or TreeIterator to get older builds.
+func (c *Client) GetTree(ctx context.Context, org, project, branch string) (*CircleTreeResponse, error) {
+	return c.GetTreePage(ctx, org, project, branch, nil)
+}
+
+// GetBuild retrieves the build with the given number.
+func (c *Client) GetBuild(ctx context.Context, org, project string, buildNum int) (*CircleBuild, error) {
 	cb := new(CircleBuild)
-	if err := client.Do(req, cb); err != nil {
+	if err := c.get(ctx, org, getBuildUri(org, project, buildNum), cb); err != nil {
 		return nil, err
 	}
 	return cb, nil
 }
 
-func GetArtifactsForBuild(org string, project string, buildNum int) ([]*CircleArtifact, error) {
-	token, err := getToken(org)
-	if err != nil {
-		return []*CircleArtifact{}, err
-	}
-	uri := getArtifactsUri(org, project, buildNum, token)
-	body, err := makeRequest("GET", uri)
-	if err != nil {
-		return []*CircleArtifact{}, err
-	}
-	defer body.Close()
-	var r io.Reader
-	if os.Getenv("CIRCLE_DEBUG") == "true" {
-		r = io.TeeReader(body, os.Stdout)
-	} else {
-		r = body
-	}
+// GetArtifactsForBuild lists the artifacts saved by the given build.
+func (c *Client) GetArtifactsForBuild(ctx context.Context, org, project string, buildNum int) ([]*CircleArtifact, error) {
 	var arts []*CircleArtifact
-	if err = json.NewDecoder(r).Decode(&arts); err != nil {
+	if err := c.get(ctx, org, getArtifactsUri(org, project, buildNum), &arts); err != nil {
 		return nil, err
 	}
 	return arts, nil
 }
 
-func DownloadArtifact(artifact *CircleArtifact, directory string, org string) error {
-	token, err := getToken(org)
-	if err != nil {
-		return err
-	}
+// DownloadArtifact saves the artifact to a file in directory. The file name
+// is prefixed with the index of the container that created the artifact.
+func (c *Client) DownloadArtifact(ctx context.Context, artifact *CircleArtifact, directory string, org string) error {
 	fname := fmt.Sprintf("%d.%s", artifact.NodeIndex, path.Base(artifact.Url))
 	fmt.Fprintf(os.Stderr, "Downloading artifact to %s\n", fname)
 	f, err := os.Create(filepath.Join(directory, fname))
@@ -339,35 +426,101 @@ func DownloadArtifact(artifact *CircleArtifact, directory string, org string) er
 		return err
 	}
 	defer f.Close()
-	url := fmt.Sprintf("%s?circle-token=%s", artifact.Url, token)
-	body, err := makeRequest("GET", url)
+	req, err := c.newRequest(ctx, org, "GET", artifact.Url, nil)
 	if err != nil {
 		return err
 	}
-	defer body.Close()
-	_, copyErr := io.Copy(f, body)
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, copyErr := io.Copy(f, resp.Body)
 	return copyErr
 }
 
-func CancelBuild(org string, project string, buildNum int) (*CircleBuild, error) {
-	token, err := getToken(org)
-	if err != nil {
-		return nil, err
-	}
-	uri := getCancelUri(org, project, buildNum, token)
-	body, err := makeRequest("POST", uri)
-	if err != nil {
+// DownloadArtifacts saves every artifact to directory, downloading up to
+// c.Concurrency artifacts at once. If a download fails, the remaining
+// downloads are canceled and the first error is returned.
+func (c *Client) DownloadArtifacts(ctx context.Context, artifacts []*CircleArtifact, directory string, org string) error {
+	return c.forEach(ctx, len(artifacts), func(ctx context.Context, i int) error {
+		return c.DownloadArtifact(ctx, artifacts[i], directory, org)
+	})
+}
+
+// CancelBuild cancels the build with the given number.
+func (c *Client) CancelBuild(ctx context.Context, org, project string, buildNum int) (*CircleBuild, error) {
+	cb := new(CircleBuild)
+	if err := c.post(ctx, org, getCancelUri(org, project, buildNum), nil, cb); err != nil {
 		return nil, err
 	}
-	defer body.Close()
-	var r io.Reader
-	if os.Getenv("CIRCLE_DEBUG") == "true" {
-		r = io.TeeReader(body, os.Stdout)
-	} else {
-		r = body
-	}
-	d := json.NewDecoder(r)
-	var cb CircleBuild
-	err = d.Decode(&cb)
-	return &cb, err
+	return cb, nil
+}
+
+func Enable(ctx context.Context, host string, org string, repoName string) error {
+	return DefaultClient.Enable(ctx, host, org, repoName)
+}
+
+func Rebuild(ctx context.Context, tb *TreeBuild) error {
+	return DefaultClient.Rebuild(ctx, tb)
+}
+
+func TriggerBuild(ctx context.Context, vcsType, org, project, branch string, opts *TriggerOptions) (*CircleBuild, error) {
+	return DefaultClient.TriggerBuild(ctx, vcsType, org, project, branch, opts)
+}
+
+func RetryBuild(ctx context.Context, vcsType, org, project string, buildNum int, opts *RebuildOptions) (*CircleBuild, error) {
+	return DefaultClient.RetryBuild(ctx, vcsType, org, project, buildNum, opts)
+}
+
+func ClearCache(ctx context.Context, vcsType, org, project string) error {
+	return DefaultClient.ClearCache(ctx, vcsType, org, project)
+}
+
+func SSHCommands(ctx context.Context, cb *CircleBuild) ([]string, error) {
+	return DefaultClient.SSHCommands(ctx, cb)
+}
+
+func GetTree(org string, project string, branch string) (*CircleTreeResponse, error) {
+	return GetTreeContext(context.Background(), org, project, branch)
+}
+
+func GetTreeContext(ctx context.Context, org, project, branch string) (*CircleTreeResponse, error) {
+	return DefaultClient.GetTree(ctx, org, project, branch)
+}
+
+func GetBuild(org string, project string, buildNum int) (*CircleBuild, error) {
+	return GetBuildContext(context.Background(), org, project, buildNum)
+}
+
+func GetBuildContext(ctx context.Context, org string, project string, buildNum int) (*CircleBuild, error) {
+	return DefaultClient.GetBuild(ctx, org, project, buildNum)
+}
+
+func GetArtifactsForBuild(org string, project string, buildNum int) ([]*CircleArtifact, error) {
+	return GetArtifactsForBuildContext(context.Background(), org, project, buildNum)
+}
+
+func GetArtifactsForBuildContext(ctx context.Context, org string, project string, buildNum int) ([]*CircleArtifact, error) {
+	return DefaultClient.GetArtifactsForBuild(ctx, org, project, buildNum)
+}
+
+func DownloadArtifact(artifact *CircleArtifact, directory string, org string) error {
+	return DownloadArtifactContext(context.Background(), artifact, directory, org)
+}
+
+func DownloadArtifactContext(ctx context.Context, artifact *CircleArtifact, directory string, org string) error {
+	return DefaultClient.DownloadArtifact(ctx, artifact, directory, org)
+}
+
+func DownloadArtifacts(ctx context.Context, artifacts []*CircleArtifact, directory string, org string) error {
+	return DefaultClient.DownloadArtifacts(ctx, artifacts, directory, org)
+}
+
+func CancelBuild(org string, project string, buildNum int) (*CircleBuild, error) {
+	return CancelBuildContext(context.Background(), org, project, buildNum)
+}
+
+func CancelBuildContext(ctx context.Context, org string, project string, buildNum int) (*CircleBuild, error) {
+	return DefaultClient.CancelBuild(ctx, org, project, buildNum)
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	circle "github.com/Shyp/go-circle"
+	"github.com/Shyp/go-circle/circlev2"
+	git "github.com/Shyp/go-git"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const approveUsage = `usage: approve [-job NAME] [branch]
+
+Approve a job that is on hold in the pipeline for the tip of a branch, or the
+current branch if none is given, so the jobs after it can run. Jobs on hold
+are the jobs with "type: approval" in the workflow configuration.
+
+With -job, approve the job with that name, or WORKFLOW/NAME if more than one
+workflow has a job with that name. Otherwise, choose from the jobs on hold.`
+
+// hold is an approval job that is waiting to be approved.
+type hold struct {
+	workflow *circlev2.Workflow
+	job      *circlev2.Job
+}
+
+func (h *hold) String() string {
+	return h.workflow.Name + "/" + h.job.Name
+}
+
+// findHold returns the hold named name, which is either the name of a job or
+// WORKFLOW/NAME.
+func findHold(holds []*hold, name string) (*hold, error) {
+	var matches []*hold
+	for _, h := range holds {
+		if h.job.Name == name || h.String() == name {
+			matches = append(matches, h)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no job named %q is waiting for approval", name)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("more than one job named %q is waiting for approval, use WORKFLOW/NAME", name)
+}
+
+// chooseHold prints the holds to w and asks which one to approve, reading the
+// answer, a number or a name, from r.
+func chooseHold(holds []*hold, r io.Reader, w io.Writer) (*hold, error) {
+	for i, h := range holds {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, h)
+	}
+	fmt.Fprintf(w, "\nApprove which job? [1-%d]: ", len(holds))
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	answer := strings.TrimSpace(line)
+	if answer == "" {
+		return nil, errors.New("no job selected")
+	}
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n < 1 || n > len(holds) {
+			return nil, fmt.Errorf("invalid choice %d, should be between 1 and %d", n, len(holds))
+		}
+		return holds[n-1], nil
+	}
+	return findHold(holds, answer)
+}
+
+// getHolds returns the jobs waiting for approval in the most recent pipeline
+// on branch for tip.
+func getHolds(ctx context.Context, v2 *circlev2.Client, vcs, org, repo, branch, tip string) ([]*hold, error) {
+	pp, err := v2.ListPipelines(ctx, vcs, org, repo, &circlev2.PipelineOptions{Branch: branch})
+	if err != nil {
+		return nil, err
+	}
+	var pipeline *circlev2.Pipeline
+	for _, p := range pp.Items {
+		if p.MatchesRevision(tip) {
+			pipeline = p
+			break
+		}
+	}
+	if pipeline == nil {
+		return nil, fmt.Errorf("no pipeline on %s for %s, has CircleCI started it yet?", branch, tip)
+	}
+	workflows, err := v2.ListWorkflows(ctx, org, pipeline.ID)
+	if err != nil {
+		return nil, err
+	}
+	var holds []*hold
+	for _, wf := range workflows {
+		if wf.Status != circlev2.WorkflowOnHold {
+			continue
+		}
+		jobs, err := v2.ListApprovalJobs(ctx, org, wf.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range jobs {
+			holds = append(holds, &hold{workflow: wf, job: j})
+		}
+	}
+	return holds, nil
+}
+
+func doApprove(ctx context.Context, flags *flag.FlagSet, name string) error {
+	args := flags.Args()
+	if len(args) > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	branch, err := getBranchFromArgs(args)
+	if err != nil {
+		return err
+	}
+	tip, err := git.Tip(branch)
+	if err != nil {
+		return err
+	}
+	remote, err := git.GetRemoteURL("origin")
+	if err != nil {
+		return err
+	}
+	org, repo := remote.Path, remote.RepoName
+	vcs, err := circle.VCSType(remote.Host)
+	if err != nil {
+		return err
+	}
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	holds, err := getHolds(tctx, circlev2.DefaultClient, vcs, org, repo, branch, tip)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if len(holds) == 0 {
+		return fmt.Errorf("no jobs on %s are waiting for approval", branch)
+	}
+	var h *hold
+	switch {
+	case name != "":
+		h, err = findHold(holds, name)
+	case terminal.IsTerminal(int(os.Stdin.Fd())):
+		fmt.Printf("Jobs waiting for approval on %s:\n\n", branch)
+		h, err = chooseHold(holds, os.Stdin, os.Stdout)
+	default:
+		names := make([]string, len(holds))
+		for i := range holds {
+			names[i] = holds[i].String()
+		}
+		err = fmt.Errorf("choose a job to approve with -job: %s", strings.Join(names, ", "))
+	}
+	if err != nil {
+		return err
+	}
+	tctx, cancel = context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := circlev2.ApproveJob(tctx, org, h.workflow.ID, h.job.ApprovalRequestID); err != nil {
+		return err
+	}
+	fmt.Printf("Approved %s on %s\n", h, branch)
+	return nil
+}
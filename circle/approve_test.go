@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	circle "github.com/Shyp/go-circle"
+	"github.com/Shyp/go-circle/circletest"
+	"github.com/Shyp/go-circle/circlev2"
+)
+
+func testHolds() []*hold {
+	deploy := &circlev2.Workflow{Name: "deploy"}
+	release := &circlev2.Workflow{Name: "release"}
+	return []*hold{
+		{workflow: deploy, job: &circlev2.Job{Name: "hold-staging"}},
+		{workflow: deploy, job: &circlev2.Job{Name: "hold"}},
+		{workflow: release, job: &circlev2.Job{Name: "hold"}},
+	}
+}
+
+func TestFindHold(t *testing.T) {
+	holds := testHolds()
+	if h, err := findHold(holds, "hold-staging"); err != nil || h != holds[0] {
+		t.Errorf("expected deploy/hold-staging, got %v, %v", h, err)
+	}
+	if h, err := findHold(holds, "release/hold"); err != nil || h != holds[2] {
+		t.Errorf("expected release/hold, got %v, %v", h, err)
+	}
+	if _, err := findHold(holds, "hold"); err == nil || !strings.Contains(err.Error(), "WORKFLOW/NAME") {
+		t.Errorf("expected an ambiguous name error, got %v", err)
+	}
+	if _, err := findHold(holds, "deploy"); err == nil {
+		t.Error("expected an error for a workflow name")
+	}
+}
+
+func TestChooseHold(t *testing.T) {
+	holds := testHolds()
+	out := new(bytes.Buffer)
+	h, err := chooseHold(holds, strings.NewReader("2\n"), out)
+	if err != nil || h != holds[1] {
+		t.Errorf("expected deploy/hold, got %v, %v", h, err)
+	}
+	want := "  1. deploy/hold-staging\n  2. deploy/hold\n  3. release/hold\n\nApprove which job? [1-3]: "
+	if out.String() != want {
+		t.Errorf("chooseHold: got %q, want %q", out.String(), want)
+	}
+	if h, err := chooseHold(holds, strings.NewReader("release/hold"), new(bytes.Buffer)); err != nil || h != holds[2] {
+		t.Errorf("expected release/hold, got %v, %v", h, err)
+	}
+	for _, answer := range []string{"\n", "4\n", "0\n", "nope\n"} {
+		if _, err := chooseHold(holds, strings.NewReader(answer), new(bytes.Buffer)); err == nil {
+			t.Errorf("expected an error for answer %q", answer)
+		}
+	}
+}
+
+func TestGetHoldsAndApprove(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "success"})
+	// An older pipeline for a different commit, with its own hold.
+	s.AddPipeline("Shyp", "go-circle", &circletest.Pipeline{
+		Revision:  "0a1b2c3d4e",
+		Workflows: []*circletest.Workflow{{Name: "deploy", Jobs: []*circletest.Job{{Name: "hold", Type: "approval"}}}},
+	})
+	deploy := &circletest.Workflow{Name: "deploy", Jobs: []*circletest.Job{
+		{Name: "build", BuildNum: b.BuildNum},
+		{Name: "hold", Type: "approval", Dependencies: []string{"build"}},
+		{Name: "deploy", Dependencies: []string{"hold"}},
+	}}
+	s.AddPipeline("Shyp", "go-circle", &circletest.Pipeline{
+		Revision:  "4e8f3c1a9d",
+		Workflows: []*circletest.Workflow{{Name: "build", Jobs: []*circletest.Job{{Name: "build", BuildNum: b.BuildNum}}}, deploy},
+	})
+	v2 := &circlev2.Client{Base: &circle.Client{
+		BaseURL: s.URL,
+		Tokens:  circle.StaticToken("token"),
+		Retry:   circle.NoRetries,
+	}}
+	ctx := context.Background()
+
+	holds, err := getHolds(ctx, v2, "github", "Shyp", "go-circle", "master", "4e8f3c1a9d5f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holds) != 1 || holds[0].String() != "deploy/hold" || holds[0].workflow.ID != deploy.ID {
+		t.Fatalf("expected deploy/hold in the latest pipeline, got %v", holds)
+	}
+	h, err := findHold(holds, "hold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v2.ApproveJob(ctx, "Shyp", h.workflow.ID, h.job.ApprovalRequestID); err != nil {
+		t.Fatal(err)
+	}
+	if deploy.Jobs[1].ApprovedBy == "" {
+		t.Error("expected the server to record the approval")
+	}
+	holds, err = getHolds(ctx, v2, "github", "Shyp", "go-circle", "master", "4e8f3c1a9d5f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holds) != 0 {
+		t.Errorf("expected no holds after approving, got %v", holds)
+	}
+
+	if _, err := getHolds(ctx, v2, "github", "Shyp", "go-circle", "master", "ffffff"); err == nil || !strings.Contains(err.Error(), "no pipeline on master") {
+		t.Errorf("expected a missing pipeline error, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	circle "github.com/Shyp/go-circle"
+	git "github.com/Shyp/go-git"
+)
+
+const envUsage = `usage: env list
+       env set KEY=VALUE...
+       env rm KEY...
+       env import FILE
+
+Manage the environment variables CircleCI sets for this project's builds.
+CircleCI never shows the values of environment variables, so "list" prints
+masked values.
+
+"import" sets every variable in a .env file. Blank lines and lines starting
+with # are skipped, and values may be quoted, for example:
+
+	export AWS_REGION=us-west-2
+	GREETING="hello world"`
+
+// envVar is a variable parsed from the command line or a .env file.
+type envVar struct {
+	name  string
+	value string
+}
+
+var envNameRx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseEnvVar parses a KEY=VALUE pair.
+func parseEnvVar(s string) (envVar, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || !envNameRx.MatchString(parts[0]) {
+		return envVar{}, fmt.Errorf("invalid environment variable %q, should look like KEY=VALUE", s)
+	}
+	return envVar{parts[0], parts[1]}, nil
+}
+
+// unquoteEnvValue strips the quotes from a .env value. Double-quoted values
+// may contain escapes like \n; single-quoted values are used as is. Unquoted
+// values end at a " #" comment.
+func unquoteEnvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("unterminated quote in %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// parseEnvFile reads the variables in a .env file, in the order they appear.
+func parseEnvFile(r io.Reader) ([]envVar, error) {
+	var vars []envVar
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		parts := strings.SplitN(line, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || !envNameRx.MatchString(name) {
+			return nil, fmt.Errorf("line %d: should look like KEY=VALUE", lineno)
+		}
+		value, err := unquoteEnvValue(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		vars = append(vars, envVar{name, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+func setEnvVars(ctx context.Context, p project, vcs string, vars []envVar) error {
+	for _, v := range vars {
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		_, err := circle.SetEnvVar(tctx, vcs, p.org, p.repoName, v.name, v.value)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("setting %s: %w", v.name, err)
+		}
+		fmt.Fprintf(os.Stderr, "Set %s on %s/%s\n", v.name, p.org, p.repoName)
+	}
+	return nil
+}
+
+func doEnv(ctx context.Context, flags *flag.FlagSet) error {
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	remote, err := git.GetRemoteURL("origin")
+	if err != nil {
+		return err
+	}
+	p := project{remote.Host, remote.Path, remote.RepoName}
+	vcs, err := circle.VCSType(p.host)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		vars, err := circle.ListEnvVars(tctx, vcs, p.org, p.repoName)
+		if err != nil {
+			return err
+		}
+		if len(vars) == 0 {
+			fmt.Fprintf(os.Stderr, "No environment variables are set on %s/%s\n", p.org, p.repoName)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, v := range vars {
+			fmt.Fprintf(w, "%s\t%s\n", v.Name, v.Value)
+		}
+		return w.Flush()
+	case "set":
+		if len(args) == 1 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		vars := make([]envVar, len(args)-1)
+		for i, arg := range args[1:] {
+			v, err := parseEnvVar(arg)
+			if err != nil {
+				return err
+			}
+			vars[i] = v
+		}
+		return setEnvVars(ctx, p, vcs, vars)
+	case "rm":
+		if len(args) == 1 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		for _, name := range args[1:] {
+			tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			err := circle.DeleteEnvVar(tctx, vcs, p.org, p.repoName, name)
+			cancel()
+			if err != nil {
+				return fmt.Errorf("removing %s: %w", name, err)
+			}
+			fmt.Fprintf(os.Stderr, "Removed %s from %s/%s\n", name, p.org, p.repoName)
+		}
+		return nil
+	case "import":
+		if len(args) != 2 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		vars, err := parseEnvFile(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", args[1], err)
+		}
+		return setEnvVars(ctx, p, vcs, vars)
+	default:
+		flags.Usage()
+		os.Exit(2)
+		return nil
+	}
+}
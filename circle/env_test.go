@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	vars, err := parseEnvFile(strings.NewReader(`# AWS settings
+export AWS_REGION=us-west-2
+AWS_SECRET = abc=def  # not part of the value
+
+GREETING="hello\nworld"
+LITERAL='$HOME \n'
+EMPTY=
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []envVar{
+		{"AWS_REGION", "us-west-2"},
+		{"AWS_SECRET", "abc=def"},
+		{"GREETING", "hello\nworld"},
+		{"LITERAL", `$HOME \n`},
+		{"EMPTY", ""},
+	}
+	if len(vars) != len(want) {
+		t.Fatalf("expected %d variables, got %v", len(want), vars)
+	}
+	for i := range want {
+		if vars[i] != want[i] {
+			t.Errorf("variable %d: expected %v, got %v", i, want[i], vars[i])
+		}
+	}
+}
+
+func TestParseEnvFileErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{"FOO=bar\nnot a variable\n", "line 2: should look like KEY=VALUE"},
+		{"1FOO=bar\n", "line 1: should look like KEY=VALUE"},
+		{"FOO='bar\n", "line 1: unterminated quote in 'bar"},
+		{`FOO="bar`, "line 1: invalid syntax"},
+	}
+	for _, tt := range tests {
+		_, err := parseEnvFile(strings.NewReader(tt.in))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("parseEnvFile(%q): expected error %q, got %v", tt.in, tt.err, err)
+		}
+	}
+}
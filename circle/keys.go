@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	circle "github.com/Shyp/go-circle"
+	git "github.com/Shyp/go-git"
+)
+
+const keysUsage = `usage: keys list
+       keys create [deploy|user]
+       keys rm FINGERPRINT...
+       keys generate HOSTNAME
+
+Manage the SSH keys for this project.
+
+"list", "create" and "rm" manage the keys CircleCI uses to check out the
+project. "create" makes a deploy key by default, or a key for your GitHub
+user with "user".
+
+"generate" makes a new ed25519 key pair, uploads the private key to CircleCI
+for builds to use when they connect to HOSTNAME, and prints the public key, to
+install on that host or as a deploy key on the code host. The private key is
+not saved anywhere else.`
+
+func doKeys(ctx context.Context, flags *flag.FlagSet) error {
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	remote, err := git.GetRemoteURL("origin")
+	if err != nil {
+		return err
+	}
+	org, repo := remote.Path, remote.RepoName
+	vcs, err := circle.VCSType(remote.Host)
+	if err != nil {
+		return err
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		keys, err := circle.ListCheckoutKeys(tctx, vcs, org, repo)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			fmt.Fprintf(os.Stderr, "No checkout keys for %s/%s\n", org, repo)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, k := range keys {
+			preferred := ""
+			if k.Preferred {
+				preferred = "preferred"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", k.Fingerprint, k.Type, preferred)
+		}
+		return w.Flush()
+	case args[0] == "create" && len(args) <= 2:
+		keyType := circle.DeployKey
+		if len(args) == 2 {
+			switch args[1] {
+			case "deploy":
+			case "user":
+				keyType = circle.UserKey
+			default:
+				return fmt.Errorf("unknown key type %q, should be deploy or user", args[1])
+			}
+		}
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		key, err := circle.CreateCheckoutKey(tctx, vcs, org, repo, keyType)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Created %s %s for %s/%s\n", key.Type, key.Fingerprint, org, repo)
+		fmt.Println(key.PublicKey)
+		return nil
+	case args[0] == "rm" && len(args) > 1:
+		for _, fingerprint := range args[1:] {
+			tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			err := circle.DeleteCheckoutKey(tctx, vcs, org, repo, fingerprint)
+			cancel()
+			if err != nil {
+				return fmt.Errorf("removing %s: %w", fingerprint, err)
+			}
+			fmt.Fprintf(os.Stderr, "Removed %s from %s/%s\n", fingerprint, org, repo)
+		}
+		return nil
+	case args[0] == "generate" && len(args) == 2:
+		hostname := args[1]
+		key, err := circle.GenerateSSHKey(fmt.Sprintf("circleci@%s/%s", org, repo))
+		if err != nil {
+			return err
+		}
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if err := circle.AddSSHKey(tctx, vcs, org, repo, hostname, key.PrivateKey); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Uploaded private key %s to %s/%s for %s\n", key.Fingerprint, org, repo, hostname)
+		fmt.Println(key.PublicKey)
+		return nil
+	default:
+		flags.Usage()
+		os.Exit(2)
+		return nil
+	}
+}
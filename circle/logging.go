@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	circle "github.com/Shyp/go-circle"
+	log "github.com/inconshreveable/log15"
+)
+
+var (
+	verbose   bool
+	debug     bool
+	traceFile string
+)
+
+// addLoggingFlags registers the logging flags on fs, so they can be passed
+// before a subcommand or before the subcommand's arguments.
+func addLoggingFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&verbose, "v", false, "Log every API request to stderr")
+	fs.BoolVar(&debug, "debug", false, "Log every API request and response body to stderr")
+	fs.StringVar(&traceFile, "trace-file", "", "Append one JSON line per API request to this file")
+}
+
+// parseFlags parses args into fs and then turns on request logging, if any of
+// the logging flags were set.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	checkError(configureLogging())
+}
+
+// loggingConfig holds the flag values the logger was last built from, so
+// parsing the subcommand flags doesn't open the trace file twice.
+type loggingConfig struct {
+	verbose   bool
+	debug     bool
+	traceFile string
+}
+
+var configured loggingConfig
+
+func configureLogging() error {
+	cfg := loggingConfig{verbose, debug, traceFile}
+	if cfg == configured {
+		return nil
+	}
+	configured = cfg
+	var handlers []log.Handler
+	if verbose || debug {
+		handlers = append(handlers, log.StreamHandler(os.Stderr, log.LogfmtFormat()))
+	}
+	if traceFile != "" {
+		h, err := log.FileHandler(traceFile, log.JsonFormat())
+		if err != nil {
+			return err
+		}
+		// The trace file gets one line per exchange; the number of retries
+		// is already in the exchange's record.
+		handlers = append(handlers, log.FilterHandler(func(r *log.Record) bool {
+			return r.Msg != circle.RetryLogMessage
+		}, h))
+	}
+	if len(handlers) == 0 {
+		return nil
+	}
+	logger := log.New()
+	logger.SetHandler(log.MultiHandler(handlers...))
+	circle.DefaultClient.Logger = logger
+	circle.DefaultClient.LogBodies = debug
+	return nil
+}
@@ -2,18 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	circle "github.com/Shyp/go-circle"
+	build "github.com/Shyp/go-circle/builds"
 	"github.com/Shyp/go-circle/wait"
 	git "github.com/Shyp/go-git"
 	"github.com/skratchdot/open-golang/open"
-	"golang.org/x/sync/errgroup"
 )
 
 const help = `The circle binary interacts with a server that runs your tests.
@@ -24,21 +28,66 @@ Usage:
 
 The commands are:
 
+	approve             Approve a job that is on hold in a workflow.
+	cache               Manage the local API cache and the CircleCI build cache.
+	disable             Disable CircleCI tests for this project.
 	enable              Enable CircleCI tests for this project.
+	env                 Manage the environment variables for this project.
+	keys                Manage the SSH keys for this project.
 	open                Open the latest branch build in a browser.
+	projects            List the projects you follow.
 	rebuild             Rebuild a given test branch.
+	recent              Show recent builds for every project you follow.
+	trigger             Start a new build on a branch.
 	update              Update to the latest version
 	version             Print the current version
 	wait                Wait for tests to finish on a branch.
 	download-artifacts  Download all artifacts.
 
 Use "circle help [command]" for more information about a command.
+
+Pass -v, --debug or --trace-file before a command, or after it and before its
+arguments, to log the API requests it makes. For example, "circle open -v
+master" logs requests, but "circle open master -v" does not. "wait" and
+"trigger" also read flags after the branch.
 `
 
 const downloadUsage = `usage: download-artifacts <build-num>`
-const enableUsage = `usage: enable [-h]
+const cacheUsage = `usage: cache clear
+       cache clear-remote [-rebuild]
+
+"circle wait" and "circle open" share API responses with other circle
+processes through a cache in your user cache directory. "cache clear" deletes
+every cached response.
+
+"cache clear-remote" deletes this project's dependency and source caches on
+CircleCI, so the next build starts from scratch. With -rebuild, it then
+rebuilds the latest build on the current branch.`
+const recentUsage = `usage: recent [-n count] [-org org]
+
+Print the most recent builds for every project you follow, across every
+branch. The API token for -org is used; it defaults to the owner of the
+"origin" remote.`
+const triggerUsage = `usage: trigger [-p KEY=VALUE]... [-revision sha] [-wait] [branch]
+
+Start a new build of branch, or of the current branch if none is given, and
+print its URL. Use -p to set build parameters, which are available to the
+build as environment variables, for example:
 
-Turn on CircleCI builds for this project.`
+	circle trigger -p RUN_EXTENDED_TESTS=true master`
+const enableUsage = `usage: enable [-h] [-host host] [org/repo...]
+
+Turn on CircleCI builds for this project, or for every project named on the
+command line.`
+const disableUsage = `usage: disable [-h] [-host host] [org/repo...]
+
+Turn off CircleCI builds for this project, or for every project named on the
+command line.`
+const projectsUsage = `usage: projects [-org org]
+
+List the projects you follow, with the latest build on each branch. If -org is
+set, only projects owned by that organization are listed and its API token is
+used; otherwise the token for the owner of the "origin" remote is used.`
 
 func usage() {
 	fmt.Fprintf(os.Stderr, help)
@@ -51,13 +100,62 @@ func init() {
 
 func checkError(err error) {
 	if err != nil {
-		os.Stderr.WriteString(err.Error() + "\n")
+		os.Stderr.WriteString(describeError(err) + "\n")
 		os.Exit(1)
 	}
 }
 
+// describeError adds a hint about how to fix common API errors.
+func describeError(err error) string {
+	var rerr *circle.RateLimitError
+	if errors.As(err, &rerr) {
+		if rerr.RetryAfter > 0 {
+			return fmt.Sprintf("%v\n\nCircleCI is rate limiting requests, try again in %s.", err, rerr.RetryAfter)
+		}
+		return fmt.Sprintf("%v\n\nCircleCI is rate limiting requests, try again later.", err)
+	}
+	var cerr *circle.Error
+	if !errors.As(err, &cerr) {
+		return err.Error()
+	}
+	switch {
+	case errors.Is(err, circle.ErrUnauthorized):
+		return fmt.Sprintf(`%v
+
+CircleCI rejected the token for org %s. Check the token in your config file,
+or go to https://circleci.com/account/api to create a new one.`, err, cerr.Org)
+	case errors.Is(err, circle.ErrNotFound):
+		return fmt.Sprintf(`%v
+
+CircleCI couldn't find that project or build. If the project is not followed,
+run "circle enable" to start building it.`, err)
+	case errors.Is(err, circle.ErrServer):
+		return fmt.Sprintf("%v\n\nCircleCI had a server error, try again in a minute.", err)
+	}
+	return err.Error()
+}
+
 // Given a set of command line args, return the git branch or an error. Returns
 // the current git branch if no argument is specified
+// parseBranchFlags parses the flags in args for a command that takes an
+// optional branch. Flags can come after the branch as well as before it, so
+// "circle trigger master -wait" works like "circle trigger -wait master". It
+// returns the branch argument, if there is one, and exits with a usage error
+// if there is more than one argument.
+func parseBranchFlags(fs *flag.FlagSet, args []string) []string {
+	parseFlags(fs, args)
+	rest := fs.Args()
+	if len(rest) == 0 {
+		return nil
+	}
+	parseFlags(fs, rest[1:])
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return rest[:1]
+}
+
 func getBranchFromArgs(args []string) (string, error) {
 	if len(args) == 0 {
 		return git.CurrentBranch()
@@ -66,13 +164,13 @@ func getBranchFromArgs(args []string) (string, error) {
 	}
 }
 
-func doOpen(flags *flag.FlagSet) {
+func doOpen(ctx context.Context, flags *flag.FlagSet) {
 	args := flags.Args()
 	branch, err := getBranchFromArgs(args)
 	checkError(err)
 	remote, err := git.GetRemoteURL("origin")
 	checkError(err)
-	cr, err := circle.GetTree(remote.Path, remote.RepoName, branch)
+	cr, err := circle.GetTreeContext(ctx, remote.Path, remote.RepoName, branch)
 	checkError(err)
 	if len(*cr) == 0 {
 		fmt.Printf("No results, are you sure there are tests for %s/%s?\n",
@@ -83,7 +181,7 @@ func doOpen(flags *flag.FlagSet) {
 	open.Start(latestBuild.BuildURL)
 }
 
-func doDownload(flags *flag.FlagSet) error {
+func doDownload(ctx context.Context, flags *flag.FlagSet) error {
 	buildStr := flags.Arg(0)
 	val, err := strconv.Atoi(buildStr)
 	if err != nil {
@@ -93,23 +191,15 @@ func doDownload(flags *flag.FlagSet) error {
 	if err != nil {
 		return err
 	}
-	arts, err := circle.GetArtifactsForBuild(remote.Path, remote.RepoName, val)
+	arts, err := circle.GetArtifactsForBuildContext(ctx, remote.Path, remote.RepoName, val)
 	if err != nil {
 		return err
 	}
-	var g errgroup.Group
 	tempDir, err := ioutil.TempDir("", "circle-artifacts")
 	if err != nil {
 		return err
 	}
-	for _, art := range arts {
-		art := art
-		g.Go(func() error {
-			return circle.DownloadArtifact(art, tempDir, remote.Path)
-		})
-	}
-
-	if err := g.Wait(); err != nil {
+	if err := circle.DownloadArtifacts(ctx, arts, tempDir, remote.Path); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return err
 	}
@@ -117,20 +207,195 @@ func doDownload(flags *flag.FlagSet) error {
 	return nil
 }
 
-func doEnable(flags *flag.FlagSet) error {
+// project is a repository on a code host.
+type project struct {
+	host     string
+	org      string
+	repoName string
+}
+
+// projectsFromArgs parses "org/repo" arguments into projects on host. If args
+// is empty, it returns the project for the "origin" remote.
+func projectsFromArgs(args []string, host string) ([]project, error) {
+	if len(args) == 0 {
+		remote, err := git.GetRemoteURL("origin")
+		if err != nil {
+			return nil, err
+		}
+		return []project{{remote.Host, remote.Path, remote.RepoName}}, nil
+	}
+	projects := make([]project, len(args))
+	for i, arg := range args {
+		parts := strings.Split(arg, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid project %q, should look like org/repo", arg)
+		}
+		projects[i] = project{host, parts[0], parts[1]}
+	}
+	return projects, nil
+}
+
+// followProjects calls follow for each project, printing the outcome, and
+// returns an error if any of them failed.
+func followProjects(ctx context.Context, projects []project, verb string, follow func(context.Context, string, string, string) error) error {
+	failed := 0
+	for _, p := range projects {
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := follow(tctx, p.host, p.org, p.repoName)
+		cancel()
+		if err != nil {
+			if len(projects) == 1 {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "%s/%s: %s\n", p.org, p.repoName, describeError(err))
+			failed++
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s %s/%s\n", verb, p.org, p.repoName)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to update %d of %d projects", failed, len(projects))
+	}
+	return nil
+}
+
+func doEnable(ctx context.Context, flags *flag.FlagSet, host string) error {
+	projects, err := projectsFromArgs(flags.Args(), host)
+	if err != nil {
+		return err
+	}
+	return followProjects(ctx, projects, "Enabled", circle.Enable)
+}
+
+func doDisable(ctx context.Context, flags *flag.FlagSet, host string) error {
+	projects, err := projectsFromArgs(flags.Args(), host)
+	if err != nil {
+		return err
+	}
+	return followProjects(ctx, projects, "Disabled", circle.Disable)
+}
+
+func doProjects(ctx context.Context, org string) error {
+	tokenOrg := org
+	if tokenOrg == "" {
+		remote, err := git.GetRemoteURL("origin")
+		if err != nil {
+			return err
+		}
+		tokenOrg = remote.Path
+	}
+	return build.GetProjects(ctx, tokenOrg, org)
+}
+
+// useCache shares API responses with other circle processes through the
+// on-disk cache. If the cache directory can't be found, we run without it.
+func useCache() {
+	cache, err := circle.NewDiskCache()
+	if err != nil {
+		return
+	}
+	circle.DefaultClient.Cache = cache
+}
+
+func doCache(ctx context.Context, flags *flag.FlagSet, rebuild *bool) error {
+	switch flags.Arg(0) {
+	case "clear":
+		cache, err := circle.NewDiskCache()
+		if err != nil {
+			return err
+		}
+		if err := cache.Clear(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Cleared the cache in %s\n", cache.Dir)
+		return nil
+	case "clear-remote":
+		// Let -rebuild come after the subcommand, too.
+		parseFlags(flags, flags.Args()[1:])
+		if flags.NArg() > 0 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		return doClearRemoteCache(ctx, *rebuild)
+	default:
+		flags.Usage()
+		os.Exit(2)
+		return nil
+	}
+}
+
+func doClearRemoteCache(ctx context.Context, rebuild bool) error {
 	remote, err := git.GetRemoteURL("origin")
 	if err != nil {
 		return err
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	vcs, err := circle.VCSType(remote.Host)
+	if err != nil {
+		return err
+	}
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	return circle.Enable(ctx, remote.Host, remote.Path, remote.RepoName)
+	if err := circle.ClearCache(tctx, vcs, remote.Path, remote.RepoName); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Cleared the CircleCI build cache for %s/%s\n", remote.Path, remote.RepoName)
+	if !rebuild {
+		return nil
+	}
+	branch, err := git.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	cr, err := circle.GetTreeContext(tctx, remote.Path, remote.RepoName, branch)
+	if err != nil {
+		return err
+	}
+	if len(*cr) == 0 {
+		return fmt.Errorf("No builds on %s to rebuild", branch)
+	}
+	latestBuild := (*cr)[0]
+	if err := circle.Rebuild(tctx, &latestBuild); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Rebuilding build %d on %s\n", latestBuild.BuildNum, branch)
+	return nil
 }
 
-func doRebuild(flags *flag.FlagSet) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	args := flags.Args()
+func doRecent(ctx context.Context, org string, limit int) error {
+	if limit < 1 || limit > circle.MaxPageSize {
+		return fmt.Errorf("-n must be between 1 and %d", circle.MaxPageSize)
+	}
+	if org == "" {
+		remote, err := git.GetRemoteURL("origin")
+		if err != nil {
+			return err
+		}
+		org = remote.Path
+	}
+	return build.GetRecentBuilds(ctx, org, limit)
+}
+
+// buildParams collects repeated -p KEY=VALUE flags.
+type buildParams map[string]string
+
+func (p buildParams) String() string {
+	pairs := make([]string, 0, len(p))
+	for k, v := range p {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p buildParams) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("invalid build parameter %q, should look like KEY=VALUE", s)
+	}
+	p[parts[0]] = parts[1]
+	return nil
+}
+
+func doTrigger(ctx context.Context, args []string, opts *circle.TriggerOptions, waitForBuild bool) error {
 	branch, err := getBranchFromArgs(args)
 	if err != nil {
 		return err
@@ -139,12 +404,65 @@ func doRebuild(flags *flag.FlagSet) error {
 	if err != nil {
 		return err
 	}
-	cr, err := circle.GetTreeContext(ctx, remote.Path, remote.RepoName, branch)
+	vcs, err := circle.VCSType(remote.Host)
 	if err != nil {
 		return err
 	}
-	latestBuild := (*cr)[0]
-	return circle.Rebuild(ctx, &latestBuild)
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	cb, err := circle.TriggerBuild(tctx, vcs, remote.Path, remote.RepoName, branch, opts)
+	cancel()
+	if err != nil {
+		return err
+	}
+	fmt.Println(cb.BuildURL)
+	if !waitForBuild {
+		return nil
+	}
+	return wait.WaitForBuild(ctx, remote.Path, remote.RepoName, int(cb.BuildNum))
+}
+
+func doRebuild(ctx context.Context, flags *flag.FlagSet, buildNum int, opts *circle.RebuildOptions) error {
+	remote, err := git.GetRemoteURL("origin")
+	if err != nil {
+		return err
+	}
+	vcs, err := circle.VCSType(remote.Host)
+	if err != nil {
+		return err
+	}
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if buildNum == 0 {
+		branch, err := getBranchFromArgs(flags.Args())
+		if err != nil {
+			return err
+		}
+		cr, err := circle.GetTreeContext(tctx, remote.Path, remote.RepoName, branch)
+		if err != nil {
+			return err
+		}
+		if len(*cr) == 0 {
+			return fmt.Errorf("No builds on %s to rebuild", branch)
+		}
+		buildNum = (*cr)[0].BuildNum
+	}
+	cb, err := circle.RetryBuild(tctx, vcs, remote.Path, remote.RepoName, buildNum, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Println(cb.BuildURL)
+	if !opts.SSH {
+		return nil
+	}
+	commands, err := wait.WaitForSSH(ctx, remote.Path, remote.RepoName, int(cb.BuildNum))
+	if err != nil {
+		return err
+	}
+	fmt.Print("\nSSH is enabled. To log in, run:\n\n")
+	for _, cmd := range commands {
+		fmt.Println("\t" + cmd)
+	}
+	return nil
 }
 
 func main() {
@@ -158,12 +476,49 @@ branch to wait for.
 `)
 		waitflags.PrintDefaults()
 	}
+	waitNoCache := waitflags.Bool("no-cache", false, "Don't share API responses with other circle processes")
 	enableflags := flag.NewFlagSet("enable", flag.ExitOnError)
 	enableflags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s\n\n", enableUsage)
 		enableflags.PrintDefaults()
 	}
+	enableHost := enableflags.String("host", "github.com", "Code host for projects named on the command line")
+	disableflags := flag.NewFlagSet("disable", flag.ExitOnError)
+	disableflags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", disableUsage)
+		disableflags.PrintDefaults()
+	}
+	disableHost := disableflags.String("host", "github.com", "Code host for projects named on the command line")
+	projectsflags := flag.NewFlagSet("projects", flag.ExitOnError)
+	projectsflags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", projectsUsage)
+		projectsflags.PrintDefaults()
+	}
+	projectsOrg := projectsflags.String("org", "", "Only list projects owned by this organization")
+	envflags := flag.NewFlagSet("env", flag.ExitOnError)
+	envflags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", envUsage)
+		envflags.PrintDefaults()
+	}
+	approveflags := flag.NewFlagSet("approve", flag.ExitOnError)
+	approveflags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", approveUsage)
+		approveflags.PrintDefaults()
+	}
+	approveJob := approveflags.String("job", "", "Name of the job to approve (default: choose interactively)")
+	keysflags := flag.NewFlagSet("keys", flag.ExitOnError)
+	keysflags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", keysUsage)
+		keysflags.PrintDefaults()
+	}
 	openflags := flag.NewFlagSet("open", flag.ExitOnError)
+	openNoCache := openflags.Bool("no-cache", false, "Don't share API responses with other circle processes")
+	cacheflags := flag.NewFlagSet("cache", flag.ExitOnError)
+	cacheflags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", cacheUsage)
+		cacheflags.PrintDefaults()
+	}
+	cacheRebuild := cacheflags.Bool("rebuild", false, "With clear-remote, rebuild the latest build on the current branch")
 	downloadflags := flag.NewFlagSet("download-artifacts", flag.ExitOnError)
 	downloadflags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s\n\n", downloadUsage)
@@ -171,31 +526,109 @@ branch to wait for.
 	}
 	rebuildflags := flag.NewFlagSet("rebuild", flag.ExitOnError)
 	rebuildflags.Usage = func() {
-		fmt.Fprintf(os.Stderr, `usage: rebuild
+		fmt.Fprintf(os.Stderr, `usage: rebuild [-without-cache] [-ssh] [-build N] [branch]
 
-Rebuild a given test branch
+Rebuild the latest build on a branch, or the current branch if none is given,
+and print the URL of the new build. Use -build to rebuild an older build. With
+-ssh, wait until the build is ready and print the command to log in to it.
 `)
 		rebuildflags.PrintDefaults()
 	}
+	rebuildWithoutCache := rebuildflags.Bool("without-cache", false, "Rebuild without restoring the dependency cache")
+	rebuildSSH := rebuildflags.Bool("ssh", false, "Rebuild with SSH enabled")
+	rebuildNum := rebuildflags.Int("build", 0, "Build number to rebuild (default: the latest build on the branch)")
+	recentflags := flag.NewFlagSet("recent", flag.ExitOnError)
+	recentflags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", recentUsage)
+		recentflags.PrintDefaults()
+	}
+	recentLimit := recentflags.Int("n", 20, "Number of builds to show")
+	recentOrg := recentflags.String("org", "", "Organization whose API token to use")
+	triggerflags := flag.NewFlagSet("trigger", flag.ExitOnError)
+	triggerflags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", triggerUsage)
+		triggerflags.PrintDefaults()
+	}
+	triggerParams := make(buildParams)
+	triggerflags.Var(triggerParams, "p", "Build parameter as KEY=VALUE (may be repeated)")
+	triggerRevision := triggerflags.String("revision", "", "Commit to build (default: the tip of the branch)")
+	triggerParallel := triggerflags.Int("parallel", 0, "Number of containers to use (default: the project setting)")
+	triggerWait := triggerflags.Bool("wait", false, "Wait for the build to complete")
 
-	flag.Parse()
+	for _, fs := range []*flag.FlagSet{flag.CommandLine, waitflags, enableflags, openflags, downloadflags, rebuildflags, cacheflags, recentflags, disableflags, projectsflags, triggerflags, envflags, keysflags, approveflags} {
+		addLoggingFlags(fs)
+	}
+	parseFlags(flag.CommandLine, os.Args[1:])
 	args := flag.Args()
 	if len(args) < 1 {
 		usage()
 		return
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		// A second signal kills the process right away.
+		signal.Stop(sigs)
+		cancel()
+	}()
 	subargs := args[1:]
 	switch flag.Arg(0) {
+	case "approve":
+		parseFlags(approveflags, subargs)
+		err := doApprove(ctx, approveflags, *approveJob)
+		checkError(err)
+	case "cache":
+		parseFlags(cacheflags, subargs)
+		err := doCache(ctx, cacheflags, cacheRebuild)
+		checkError(err)
+	case "disable":
+		parseFlags(disableflags, subargs)
+		err := doDisable(ctx, disableflags, *disableHost)
+		checkError(err)
 	case "enable":
-		enableflags.Parse(subargs)
-		err := doEnable(enableflags)
+		parseFlags(enableflags, subargs)
+		err := doEnable(ctx, enableflags, *enableHost)
+		checkError(err)
+	case "env":
+		parseFlags(envflags, subargs)
+		err := doEnv(ctx, envflags)
+		checkError(err)
+	case "keys":
+		parseFlags(keysflags, subargs)
+		err := doKeys(ctx, keysflags)
 		checkError(err)
 	case "open":
-		openflags.Parse(subargs)
-		doOpen(openflags)
+		parseFlags(openflags, subargs)
+		if !*openNoCache {
+			useCache()
+		}
+		doOpen(ctx, openflags)
+	case "projects":
+		parseFlags(projectsflags, subargs)
+		err := doProjects(ctx, *projectsOrg)
+		checkError(err)
 	case "rebuild":
-		rebuildflags.Parse(subargs)
-		err := doRebuild(rebuildflags)
+		parseFlags(rebuildflags, subargs)
+		opts := &circle.RebuildOptions{NoCache: *rebuildWithoutCache, SSH: *rebuildSSH}
+		err := doRebuild(ctx, rebuildflags, *rebuildNum, opts)
+		checkError(err)
+	case "recent":
+		parseFlags(recentflags, subargs)
+		err := doRecent(ctx, *recentOrg, *recentLimit)
+		checkError(err)
+	case "trigger":
+		args := parseBranchFlags(triggerflags, subargs)
+		opts := &circle.TriggerOptions{
+			Revision: *triggerRevision,
+			Parallel: *triggerParallel,
+		}
+		if len(triggerParams) > 0 {
+			opts.BuildParameters = triggerParams
+		}
+		err := doTrigger(ctx, args, opts, *triggerWait)
 		checkError(err)
 	case "update":
 		err := equinoxUpdate()
@@ -204,19 +637,21 @@ Rebuild a given test branch
 		fmt.Fprintf(os.Stderr, "circle version %s\n", circle.VERSION)
 		os.Exit(1)
 	case "wait":
-		waitflags.Parse(subargs)
-		args := waitflags.Args()
+		args := parseBranchFlags(waitflags, subargs)
+		if !*waitNoCache {
+			useCache()
+		}
 		branch, err := getBranchFromArgs(args)
 		checkError(err)
-		err = wait.Wait(branch)
+		err = wait.WaitContext(ctx, branch)
 		checkError(err)
 	case "download-artifacts":
 		if len(args) == 1 {
 			fmt.Fprintf(os.Stderr, "usage: download-artifacts <build-number>\n")
 			os.Exit(1)
 		}
-		downloadflags.Parse(subargs)
-		err := doDownload(downloadflags)
+		parseFlags(downloadflags, subargs)
+		err := doDownload(ctx, downloadflags)
 		checkError(err)
 	default:
 		usage()
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestParseBranchFlags(t *testing.T) {
+	tests := []struct {
+		args   []string
+		branch string
+	}{
+		{[]string{"-wait", "master"}, "master"},
+		{[]string{"master", "-wait"}, "master"},
+		{[]string{"-wait"}, ""},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("trigger", flag.ContinueOnError)
+		w := fs.Bool("wait", false, "")
+		args := parseBranchFlags(fs, tt.args)
+		branch := ""
+		if len(args) > 0 {
+			branch = args[0]
+		}
+		if branch != tt.branch || !*w {
+			t.Errorf("parseBranchFlags(%q): got branch %q and -wait %t, want %q and true", tt.args, branch, *w, tt.branch)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package circle
 
 import (
+	"context"
 	"fmt"
 	"testing"
+
+	"github.com/Shyp/go-circle/circletest"
 )
 
 func TestBuild(t *testing.T) {
@@ -13,3 +16,108 @@ func TestBuild(t *testing.T) {
 	}
 	fmt.Println(build.Statistics())
 }
+
+func TestTriggerBuild(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "feature/x", Status: "success"})
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: NoRetries}
+	revision := "1d79f2b877c86ac0964f3fe69a0171926aa6f1d8"
+	cb, err := c.TriggerBuild(context.Background(), "github", "Shyp", "go-circle", "feature/x", &TriggerOptions{
+		Revision:        revision,
+		Parallel:        3,
+		BuildParameters: map[string]string{"RUN_EXTENDED_TESTS": "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.BuildNum != 2 || cb.Branch != "feature/x" || cb.VCSRevision != revision || cb.Parallel != 3 {
+		t.Errorf("bad build: %#v", cb)
+	}
+	if cb.BuildURL != s.URL+"/gh/Shyp/go-circle/2" {
+		t.Errorf("bad build URL: %s", cb.BuildURL)
+	}
+	b, ok := s.Build("Shyp", "go-circle", 2)
+	if !ok {
+		t.Fatal("expected the server to have build 2")
+	}
+	if b.BuildParameters["RUN_EXTENDED_TESTS"] != "true" {
+		t.Errorf("expected build parameters to be sent, got %v", b.BuildParameters)
+	}
+
+	cb, err = c.TriggerBuild(context.Background(), "github", "Shyp", "go-circle", "feature/x", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.VCSRevision != revision {
+		t.Errorf("expected to build the tip of the branch %s, got %s", revision, cb.VCSRevision)
+	}
+}
+
+func TestRetryBuild(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Status: "failed", Parallel: 2})
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Status: "success"})
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: NoRetries}
+	ctx := context.Background()
+	cb, err := c.RetryBuild(ctx, "github", "Shyp", "go-circle", 1, &RebuildOptions{NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s.Build("Shyp", "go-circle", int(cb.BuildNum)); !b.NoCache || b.SSH {
+		t.Errorf("expected a rebuild without cache, got %#v", b)
+	}
+
+	cb, err = c.RetryBuild(ctx, "github", "Shyp", "go-circle", 1, &RebuildOptions{SSH: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.BuildNum != 4 {
+		t.Errorf("expected build 4, got %d", cb.BuildNum)
+	}
+	commands, err := c.SSHCommands(ctx, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ssh -p 64535 127.0.0.1", "ssh -p 64535 127.0.0.2"}
+	if len(commands) != len(want) {
+		t.Fatalf("expected %d commands, got %q", len(want), commands)
+	}
+	for i := range want {
+		if commands[i] != want[i] {
+			t.Errorf("command %d: got %q, want %q", i, commands[i], want[i])
+		}
+	}
+}
+
+func TestSSHCommandRx(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		// CircleCI 1.0
+		{"You can now SSH into this box for 30 minutes.\n    $ ssh -p 64535 ubuntu@54.90.1.2\n", "ssh -p 64535 ubuntu@54.90.1.2"},
+		// CircleCI 2.0
+		{"You can now SSH into this box if your SSH public key is added:\n    $ ssh -p 64535 34.227.1.2\n\nUse the same SSH public key", "ssh -p 64535 34.227.1.2"},
+		{"Waiting for SSH to be enabled...\n", ""},
+	}
+	for _, tt := range tests {
+		if got := sshCommandRx.FindString(tt.output); got != tt.want {
+			t.Errorf("FindString(%q): got %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestClearCache(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddProject("github", "Shyp", "go-circle")
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: NoRetries}
+	if err := c.ClearCache(context.Background(), "github", "Shyp", "go-circle"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.CacheCleared("Shyp", "go-circle"); n != 1 {
+		t.Errorf("expected the cache to be cleared once, got %d", n)
+	}
+}
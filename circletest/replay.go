@@ -0,0 +1,234 @@
+package circletest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fixture is a single recorded HTTP exchange.
+type Fixture struct {
+	Method string `json:"method"`
+	// Path is the request path and query string, with the API token
+	// removed.
+	Path        string `json:"path"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	// Body is the response body. If the body was not valid JSON, it is
+	// stored as a JSON string and BodyText is true.
+	Body     json.RawMessage `json:"body"`
+	BodyText bool            `json:"body_text,omitempty"`
+}
+
+// fixtureKey returns a key for a request that ignores the API token and the
+// order of query parameters.
+func fixtureKey(method string, u *url.URL) string {
+	query := u.Query()
+	query.Del("circle-token")
+	key := method + " " + u.Path
+	if len(query) > 0 {
+		key += "?" + query.Encode()
+	}
+	return key
+}
+
+var unsafeFilename = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fixtureFilename returns the file a fixture with the given key is stored in.
+func fixtureFilename(key string) string {
+	return strings.Trim(unsafeFilename.ReplaceAllString(key, "_"), "_") + ".json"
+}
+
+var emailRx = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// ScrubbedEmail replaces every email address in recorded fixtures.
+const ScrubbedEmail = "user@example.com"
+
+// Recorder is an http.RoundTripper that makes requests with Transport and
+// saves each response as a Fixture in Dir. API tokens and email addresses are
+// scrubbed from the saved fixtures; the response returned to the caller is
+// unchanged.
+type Recorder struct {
+	Dir       string
+	Transport http.RoundTripper
+
+	// Scrub lists extra strings to replace with "REDACTED" in saved
+	// fixtures, for example private repository names.
+	Scrub []string
+}
+
+// NewRecorder returns a Recorder that saves fixtures to dir. If rt is nil,
+// http.DefaultTransport is used.
+func NewRecorder(dir string, rt http.RoundTripper) *Recorder {
+	return &Recorder{Dir: dir, Transport: rt}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := r.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	secrets := append([]string{}, r.Scrub...)
+	if token := requestToken(req); token != "" {
+		secrets = append(secrets, token)
+	}
+	scrubbed := string(body)
+	for _, secret := range secrets {
+		if secret != "" {
+			scrubbed = strings.Replace(scrubbed, secret, "REDACTED", -1)
+		}
+	}
+	scrubbed = emailRx.ReplaceAllString(scrubbed, ScrubbedEmail)
+
+	key := fixtureKey(req.Method, req.URL)
+	f := Fixture{
+		Method:      req.Method,
+		Path:        strings.TrimPrefix(key, req.Method+" "),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if json.Valid([]byte(scrubbed)) {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(scrubbed), "", "  "); err != nil {
+			return nil, err
+		}
+		f.Body = buf.Bytes()
+	} else {
+		f.Body, _ = json.Marshal(scrubbed)
+		f.BodyText = true
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(r.Dir, fixtureFilename(key)), append(data, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper that answers requests from the fixtures
+// in a directory, without making any network requests. Requests are matched
+// on method, path and query string; the API token is ignored.
+type Replayer struct {
+	Dir string
+
+	once     sync.Once
+	loadErr  error
+	fixtures map[string]*Fixture
+}
+
+// NewReplayer returns a Replayer that serves the fixtures in dir.
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{Dir: dir}
+}
+
+// NewReplayClient returns an http.Client that serves the fixtures in dir. Set
+// it as a circle.Client's HTTPClient to run the client against recorded
+// responses.
+func NewReplayClient(dir string) *http.Client {
+	return &http.Client{Transport: NewReplayer(dir)}
+}
+
+func (r *Replayer) load() error {
+	r.once.Do(func() {
+		r.fixtures = make(map[string]*Fixture)
+		files, err := filepath.Glob(filepath.Join(r.Dir, "*.json"))
+		if err != nil {
+			r.loadErr = err
+			return
+		}
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				r.loadErr = err
+				return
+			}
+			f := new(Fixture)
+			if err := json.Unmarshal(data, f); err != nil {
+				r.loadErr = fmt.Errorf("circletest: invalid fixture %s: %v", file, err)
+				return
+			}
+			u, err := url.Parse(f.Path)
+			if err != nil {
+				r.loadErr = fmt.Errorf("circletest: invalid fixture path %s: %v", file, err)
+				return
+			}
+			r.fixtures[fixtureKey(f.Method, u)] = f
+		}
+	})
+	return r.loadErr
+}
+
+// Keys returns the keys of every loaded fixture, sorted, for debugging.
+func (r *Replayer) Keys() ([]string, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(r.fixtures))
+	for k := range r.fixtures {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	key := fixtureKey(req.Method, req.URL)
+	f, ok := r.fixtures[key]
+	if !ok {
+		return nil, fmt.Errorf("circletest: no fixture for %s in %s", key, r.Dir)
+	}
+	body := []byte(f.Body)
+	if f.BodyText {
+		var s string
+		if err := json.Unmarshal(f.Body, &s); err != nil {
+			return nil, err
+		}
+		body = []byte(s)
+	}
+	header := make(http.Header)
+	if f.ContentType != "" {
+		header.Set("Content-Type", f.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package circletest_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	circle "github.com/Shyp/go-circle"
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{
+		Status: "failed",
+		Steps: []circletest.Step{{Name: "make test", Actions: []circletest.Action{
+			{Status: "failed", Output: "mailed kev@example.org with token s3cret-token"},
+		}}},
+	})
+	dir, err := ioutil.TempDir("", "circletest-fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	rec := &circle.Client{
+		BaseURL:    s.URL,
+		Tokens:     circle.StaticToken("s3cret-token"),
+		HTTPClient: &http.Client{Transport: circletest.NewRecorder(dir, nil)},
+	}
+	cb, err := rec.GetBuild(ctx, "Shyp", "go-circle", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.FailureTexts(ctx, cb); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 fixtures, got %d", len(files))
+	}
+	for _, file := range files {
+		data, _ := ioutil.ReadFile(file)
+		if strings.Contains(string(data), "s3cret-token") || strings.Contains(string(data), "kev@example.org") {
+			t.Errorf("fixture %s was not scrubbed:\n%s", file, data)
+		}
+	}
+
+	// Replay against a closed server to be sure nothing hits the network.
+	s.Close()
+	rep := &circle.Client{
+		BaseURL:    s.URL,
+		Tokens:     circle.StaticToken("other-token"),
+		HTTPClient: circletest.NewReplayClient(dir),
+		Retry:      circle.NoRetries,
+	}
+	cb, err = rep.GetBuild(ctx, "Shyp", "go-circle", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts, err := rep.FailureTexts(ctx, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 1 || !strings.Contains(texts[0], circletest.ScrubbedEmail) || !strings.Contains(texts[0], "REDACTED") {
+		t.Errorf("bad replayed failure text: %q", texts)
+	}
+	if _, err := rep.GetBuild(ctx, "Shyp", "go-circle", 2); err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Errorf("expected a missing fixture error, got %v", err)
+	}
+}
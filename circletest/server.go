@@ -0,0 +1,1085 @@
+// Package circletest implements a fake CircleCI API server for use in tests.
+//
+// The server keeps an in-memory model of projects and builds and serves the
+// v1 and v1.1 endpoints used by the circle package, and the v2 pipeline,
+// workflow and job endpoints used by the circlev2 package. Point a circle.Client at
+// it by setting the client's BaseURL to Server.URL:
+//
+//	s := circletest.NewServer()
+//	defer s.Close()
+//	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "queued"})
+//	s.Script("Shyp", "go-circle", 1, "running", "failed")
+//	client := &circle.Client{BaseURL: s.URL, Tokens: circle.StaticToken("token")}
+package circletest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake CircleCI API server.
+type Server struct {
+	*httptest.Server
+
+	// Token, if set, must be sent with every request, in the Circle-Token
+	// header or the circle-token query parameter, or the server responds with
+	// a 401.
+	Token string
+
+	// PageSize is the number of items in each page of a v2 list. Defaults
+	// to 20.
+	PageSize int
+
+	mu       sync.Mutex
+	projects map[string]*Project
+	// ids is the last number used to make a v2 ID.
+	ids int
+}
+
+// Project is a CircleCI project in the fake server's model.
+type Project struct {
+	VCSType   string
+	Username  string
+	RepoName  string
+	Following bool
+	// DefaultBranch defaults to "master".
+	DefaultBranch string
+	Builds        []*Build
+	// EnvVars are the project's environment variables, by name.
+	EnvVars map[string]string
+	// CacheCleared is the number of times the project's build cache has
+	// been cleared.
+	CacheCleared int
+	// CheckoutKeys and SSHKeys are the keys added to the project.
+	CheckoutKeys []*CheckoutKey
+	SSHKeys      []SSHKey
+	// Pipelines are the project's v2 pipelines, oldest first.
+	Pipelines []*Pipeline
+}
+
+// CheckoutKey is a key used to check out a project.
+type CheckoutKey struct {
+	PublicKey   string
+	Type        string
+	Fingerprint string
+	Preferred   bool
+}
+
+// SSHKey is a private key uploaded for builds to use.
+type SSHKey struct {
+	Hostname   string
+	PrivateKey string
+}
+
+// Build is a build in the fake server's model. Fields left empty when the
+// build is added are filled in with defaults by AddBuild.
+type Build struct {
+	BuildNum    int
+	Branch      string
+	VCSRevision string
+	Status      string
+	Parallel    int
+	QueuedAt    time.Time
+	StartTime   time.Time
+	StopTime    time.Time
+	Steps       []Step
+	Artifacts   []Artifact
+	Tests       []TestResult
+
+	// BuildParameters are the parameters the build was triggered with.
+	BuildParameters map[string]string
+
+	// NoCache and SSH are set on builds retried without the cache or with
+	// SSH enabled.
+	NoCache bool
+	SSH     bool
+
+	// Previous is the build number of the previous build on the same
+	// branch, or 0 if there isn't one.
+	Previous int
+
+	script []string
+}
+
+// Step is a build step; it has one Action per container.
+type Step struct {
+	Name    string
+	Actions []Action
+}
+
+// Action is the run of a Step on a single container.
+type Action struct {
+	Name    string
+	Status  string
+	Runtime time.Duration
+	Output  string
+}
+
+// TestResult is the result of a test, as stored by store_test_results.
+type TestResult struct {
+	Name      string
+	Classname string
+	File      string
+	// Result is "success", "failure", "error" or "skipped".
+	Result  string
+	RunTime time.Duration
+	Message string
+}
+
+// Artifact is a file saved by a build.
+type Artifact struct {
+	Path      string
+	NodeIndex int
+	Body      string
+}
+
+// NewServer starts and returns a new Server. The caller should call Close
+// when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		projects: make(map[string]*Project),
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// NewTLSServer starts and returns a new Server using TLS. Use the Server's
+// Certificate or Client methods to get a client that trusts it.
+func NewTLSServer() *Server {
+	s := &Server{
+		projects: make(map[string]*Project),
+	}
+	s.Server = httptest.NewTLSServer(s)
+	return s
+}
+
+func projectKey(org, repo string) string {
+	return strings.ToLower(org + "/" + repo)
+}
+
+// AddProject adds a project to the model, or returns the existing project if
+// it has already been added.
+func (s *Server) AddProject(vcsType, org, repo string) *Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addProject(vcsType, org, repo)
+}
+
+func (s *Server) addProject(vcsType, org, repo string) *Project {
+	key := projectKey(org, repo)
+	if p, ok := s.projects[key]; ok {
+		return p
+	}
+	p := &Project{VCSType: vcsType, Username: org, RepoName: repo, DefaultBranch: "master"}
+	s.projects[key] = p
+	return p
+}
+
+// AddBuild adds b to the project identified by org and repo, creating a
+// GitHub project if none exists. If b.BuildNum is zero, the next build number
+// for the project is used. AddBuild returns b.
+func (s *Server) AddBuild(org, repo string, b *Build) *Build {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.addProject("github", org, repo)
+	s.addBuild(p, b)
+	return b
+}
+
+func (s *Server) addBuild(p *Project, b *Build) {
+	if b.BuildNum == 0 {
+		b.BuildNum = len(p.Builds) + 1
+	}
+	if b.Branch == "" {
+		b.Branch = "master"
+	}
+	if b.VCSRevision == "" {
+		b.VCSRevision = fmt.Sprintf("%040x", b.BuildNum)
+	}
+	if b.Status == "" {
+		b.Status = "queued"
+	}
+	if b.Parallel == 0 {
+		b.Parallel = 1
+	}
+	if b.QueuedAt.IsZero() {
+		b.QueuedAt = time.Now().UTC()
+	}
+	if b.Previous == 0 {
+		for i := len(p.Builds) - 1; i >= 0; i-- {
+			if p.Builds[i].Branch == b.Branch {
+				b.Previous = p.Builds[i].BuildNum
+				break
+			}
+		}
+	}
+	p.Builds = append(p.Builds, b)
+	sort.Slice(p.Builds, func(i, j int) bool {
+		return p.Builds[i].BuildNum < p.Builds[j].BuildNum
+	})
+}
+
+// SetStatus sets the status of a build.
+func (s *Server) SetStatus(org, repo string, buildNum int, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b := s.build(org, repo, buildNum); b != nil {
+		setStatus(b, status)
+	}
+}
+
+// Script queues up status changes for a build. Each time the build is
+// fetched, either directly or as part of a tree, it moves to the next status
+// in the list, after the response has been written. Once the list is
+// exhausted the build stays in the last status.
+func (s *Server) Script(org, repo string, buildNum int, statuses ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b := s.build(org, repo, buildNum); b != nil {
+		b.script = append(b.script, statuses...)
+	}
+}
+
+// Build returns a copy of the build with the given number, and whether it
+// exists.
+func (s *Server) Build(org, repo string, buildNum int) (Build, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.build(org, repo, buildNum)
+	if b == nil {
+		return Build{}, false
+	}
+	return *b, true
+}
+
+// Following reports whether the project is being followed.
+func (s *Server) Following(org, repo string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[projectKey(org, repo)]
+	return ok && p.Following
+}
+
+// EnvVar returns the unmasked value of an environment variable set on the
+// project, and whether it's set.
+func (s *Server) EnvVar(org, repo, name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[projectKey(org, repo)]
+	if !ok {
+		return "", false
+	}
+	value, ok := p.EnvVars[name]
+	return value, ok
+}
+
+// CacheCleared returns the number of times the project's build cache has
+// been cleared.
+func (s *Server) CacheCleared(org, repo string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[projectKey(org, repo)]
+	if !ok {
+		return 0
+	}
+	return p.CacheCleared
+}
+
+// SSHKeys returns the SSH keys uploaded to the project.
+func (s *Server) SSHKeys(org, repo string) []SSHKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[projectKey(org, repo)]
+	if !ok {
+		return nil
+	}
+	return append([]SSHKey(nil), p.SSHKeys...)
+}
+
+func (s *Server) build(org, repo string, buildNum int) *Build {
+	p, ok := s.projects[projectKey(org, repo)]
+	if !ok {
+		return nil
+	}
+	for _, b := range p.Builds {
+		if b.BuildNum == buildNum {
+			return b
+		}
+	}
+	return nil
+}
+
+func isTerminal(status string) bool {
+	switch status {
+	case "success", "fixed", "failed", "timedout", "no_tests", "infrastructure_fail", "canceled", "retried", "not_run":
+		return true
+	}
+	return false
+}
+
+func setStatus(b *Build, status string) {
+	b.Status = status
+	now := time.Now().UTC()
+	if status == "running" && b.StartTime.IsZero() {
+		b.StartTime = now
+	}
+	if isTerminal(status) && b.StopTime.IsZero() {
+		if b.StartTime.IsZero() {
+			b.StartTime = now
+		}
+		b.StopTime = now
+	}
+}
+
+// lifecycle returns the lifecycle CircleCI reports for a build with the given
+// status.
+func lifecycle(status string) string {
+	switch {
+	case status == "running":
+		return "running"
+	case isTerminal(status):
+		return "finished"
+	}
+	return status
+}
+
+// outcome returns the outcome CircleCI reports for a build with the given
+// status, or nil if the build hasn't finished.
+func outcome(status string) interface{} {
+	switch {
+	case status == "success" || status == "fixed":
+		return "success"
+	case isTerminal(status):
+		return status
+	}
+	return nil
+}
+
+// advance moves the build to the next scripted status, if there is one.
+func advance(b *Build) {
+	if len(b.script) == 0 {
+		return
+	}
+	setStatus(b, b.script[0])
+	b.script = b.script[1:]
+}
+
+type route struct {
+	vcsType string
+	org     string
+	repo    string
+	rest    []string
+}
+
+// parsePath splits an escaped request path into the project and the remaining
+// segments, which are unescaped, so branch names can contain slashes. v1 paths
+// don't include the VCS type.
+func parsePath(path string) (route, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := range parts {
+		if part, err := url.PathUnescape(parts[i]); err == nil {
+			parts[i] = part
+		}
+	}
+	switch {
+	case len(parts) >= 4 && parts[0] == "v1" && parts[1] == "project":
+		return route{vcsType: "github", org: parts[2], repo: parts[3], rest: parts[4:]}, true
+	case len(parts) >= 5 && parts[0] == "v1.1" && parts[1] == "project":
+		return route{vcsType: parts[2], org: parts[3], repo: parts[4], rest: parts[5:]}, true
+	}
+	return route{}, false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"message": msg})
+}
+
+// requestToken returns the token sent in the Circle-Token header, or in the
+// circle-token query parameter.
+func requestToken(r *http.Request) string {
+	if token := r.Header.Get("Circle-Token"); token != "" {
+		return token
+	}
+	return r.URL.Query().Get("circle-token")
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" && requestToken(r) != s.Token {
+		writeMessage(w, http.StatusUnauthorized, "You must log in first.")
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/artifacts/") {
+		s.serveArtifact(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/v2/") {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.serveV2(w, r)
+		return
+	}
+	if (r.URL.Path == "/v1/projects" || r.URL.Path == "/v1.1/projects") && r.Method == "GET" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.serveProjects(w)
+		return
+	}
+	if (r.URL.Path == "/v1/recent-builds" || r.URL.Path == "/v1.1/recent-builds") && r.Method == "GET" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.serveRecent(w, r)
+		return
+	}
+	rt, ok := parsePath(r.URL.EscapedPath())
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Not found")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[projectKey(rt.org, rt.repo)]
+	if len(rt.rest) == 1 && rt.rest[0] == "follow" && r.Method == "POST" {
+		if !ok {
+			p = s.addProject(rt.vcsType, rt.org, rt.repo)
+		}
+		p.Following = true
+		resp := map[string]interface{}{"following": true, "first_build": nil}
+		if len(p.Builds) > 0 {
+			resp["first_build"] = s.renderBuild(p, p.Builds[len(p.Builds)-1], true)
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	if len(rt.rest) == 1 && rt.rest[0] == "unfollow" && r.Method == "POST" {
+		if ok {
+			p.Following = false
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"following": false})
+		return
+	}
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if len(rt.rest) >= 1 && len(rt.rest) <= 2 && rt.rest[0] == "envvar" {
+		s.serveEnvVars(w, r, p, rt.rest[1:])
+		return
+	}
+	if len(rt.rest) >= 1 && len(rt.rest) <= 2 && rt.rest[0] == "checkout-key" {
+		s.serveCheckoutKeys(w, r, p, rt.rest[1:])
+		return
+	}
+	if len(rt.rest) == 1 && rt.rest[0] == "build-cache" && r.Method == "DELETE" {
+		p.CacheCleared++
+		writeJSON(w, http.StatusOK, map[string]string{"status": "build caches deleted"})
+		return
+	}
+	if len(rt.rest) == 1 && rt.rest[0] == "ssh-key" && r.Method == "POST" {
+		s.serveAddSSHKey(w, r, p)
+		return
+	}
+	if len(rt.rest) == 2 && rt.rest[0] == "tree" && r.Method == "GET" {
+		s.serveTree(w, r, p, rt.rest[1])
+		return
+	}
+	if (len(rt.rest) == 0 || len(rt.rest) == 2 && rt.rest[0] == "tree") && r.Method == "POST" {
+		branch := p.DefaultBranch
+		if len(rt.rest) == 2 {
+			branch = rt.rest[1]
+		}
+		s.serveTrigger(w, r, p, branch)
+		return
+	}
+	if len(rt.rest) == 0 && r.Method == "GET" {
+		s.serveTree(w, r, p, "")
+		return
+	}
+	if len(rt.rest) == 0 {
+		writeMessage(w, http.StatusNotFound, "Not found")
+		return
+	}
+	buildNum, err := strconv.Atoi(rt.rest[0])
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Not found")
+		return
+	}
+	b := s.build(rt.org, rt.repo, buildNum)
+	if b == nil {
+		writeMessage(w, http.StatusNotFound, "Build not found")
+		return
+	}
+	switch {
+	case len(rt.rest) == 1 && r.Method == "GET":
+		writeJSON(w, http.StatusOK, s.renderBuild(p, b, true))
+		advance(b)
+	case len(rt.rest) == 2 && rt.rest[1] == "tests" && r.Method == "GET":
+		s.serveTests(w, b)
+	case len(rt.rest) == 2 && rt.rest[1] == "artifacts" && r.Method == "GET":
+		s.serveArtifacts(w, p, b)
+	case len(rt.rest) == 2 && rt.rest[1] == "cancel" && r.Method == "POST":
+		b.script = nil
+		setStatus(b, "canceled")
+		writeJSON(w, http.StatusOK, s.renderBuild(p, b, true))
+	case len(rt.rest) == 2 && (rt.rest[1] == "retry" || rt.rest[1] == "ssh") && r.Method == "POST":
+		s.serveRetry(w, r, p, b, rt.rest[1] == "ssh")
+	case len(rt.rest) == 4 && rt.rest[1] == "output" && r.Method == "GET":
+		s.serveOutput(w, b, rt.rest[2], rt.rest[3])
+	default:
+		writeMessage(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// matchesFilter reports whether a build with the given status is included by
+// the "filter" query parameter of a build list.
+func matchesFilter(filter, status string) bool {
+	switch filter {
+	case "":
+		return true
+	case "completed":
+		return isTerminal(status)
+	case "successful":
+		return status == "success" || status == "fixed"
+	case "failed":
+		return status == "failed" || status == "timedout" || status == "no_tests" || status == "infrastructure_fail"
+	case "running":
+		return status == "running"
+	}
+	return false
+}
+
+// SSHPort is the port in the ssh command printed by builds retried with SSH.
+const SSHPort = 64535
+
+// serveRetry starts a new build of the same revision as b. If ssh is true, the
+// new build gets an "Enable SSH" step that prints an ssh command for each
+// container.
+func (s *Server) serveRetry(w http.ResponseWriter, r *http.Request, p *Project, b *Build, ssh bool) {
+	var body struct {
+		NoCache bool `json:"no_cache"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Invalid JSON body")
+			return
+		}
+	}
+	nb := &Build{
+		Branch:      b.Branch,
+		VCSRevision: b.VCSRevision,
+		Parallel:    b.Parallel,
+		BuildNum:    p.Builds[len(p.Builds)-1].BuildNum + 1,
+		NoCache:     body.NoCache,
+		SSH:         ssh,
+	}
+	if ssh {
+		step := Step{Name: "Enable SSH"}
+		for i := 0; i < nb.Parallel; i++ {
+			step.Actions = append(step.Actions, Action{
+				Name:   "Enable SSH",
+				Status: "success",
+				Output: fmt.Sprintf("You can now SSH into this box if your SSH public key is added:\n    $ ssh -p %d 127.0.0.%d\n", SSHPort, i+1),
+			})
+		}
+		nb.Steps = append(nb.Steps, step)
+	}
+	s.addBuild(p, nb)
+	writeJSON(w, http.StatusOK, s.renderBuild(p, nb, true))
+}
+
+// serveTrigger starts a new build on branch, using the revision, parallel and
+// build_parameters fields of the request body.
+func (s *Server) serveTrigger(w http.ResponseWriter, r *http.Request, p *Project, branch string) {
+	var body struct {
+		Revision        string            `json:"revision"`
+		Tag             string            `json:"tag"`
+		Parallel        int               `json:"parallel"`
+		BuildParameters map[string]string `json:"build_parameters"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Invalid JSON body")
+			return
+		}
+	}
+	b := &Build{
+		Branch:          branch,
+		VCSRevision:     body.Revision,
+		Parallel:        body.Parallel,
+		BuildParameters: body.BuildParameters,
+	}
+	if b.VCSRevision == "" {
+		for i := len(p.Builds) - 1; i >= 0; i-- {
+			if p.Builds[i].Branch == branch {
+				b.VCSRevision = p.Builds[i].VCSRevision
+				break
+			}
+		}
+	}
+	if len(p.Builds) > 0 {
+		b.BuildNum = p.Builds[len(p.Builds)-1].BuildNum + 1
+	}
+	s.addBuild(p, b)
+	writeJSON(w, http.StatusCreated, s.renderBuild(p, b, true))
+}
+
+// maskEnvVar hides an environment variable value the way CircleCI does,
+// keeping only the last four characters.
+func maskEnvVar(value string) string {
+	if len(value) <= 4 {
+		return "xxxx"
+	}
+	return "xxxx" + value[len(value)-4:]
+}
+
+func (s *Server) serveEnvVars(w http.ResponseWriter, r *http.Request, p *Project, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == "GET":
+		names := make([]string, 0, len(p.EnvVars))
+		for name := range p.EnvVars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		vars := make([]map[string]string, len(names))
+		for i, name := range names {
+			vars[i] = map[string]string{"name": name, "value": maskEnvVar(p.EnvVars[name])}
+		}
+		writeJSON(w, http.StatusOK, vars)
+	case len(rest) == 0 && r.Method == "POST":
+		var body struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+			writeMessage(w, http.StatusBadRequest, "Invalid environment variable")
+			return
+		}
+		if p.EnvVars == nil {
+			p.EnvVars = make(map[string]string)
+		}
+		p.EnvVars[body.Name] = body.Value
+		writeJSON(w, http.StatusCreated, map[string]string{"name": body.Name, "value": maskEnvVar(body.Value)})
+	case len(rest) == 1 && r.Method == "GET":
+		value, ok := p.EnvVars[rest[0]]
+		if !ok {
+			writeMessage(w, http.StatusNotFound, "Environment variable not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"name": rest[0], "value": maskEnvVar(value)})
+	case len(rest) == 1 && r.Method == "DELETE":
+		delete(p.EnvVars, rest[0])
+		writeMessage(w, http.StatusOK, "ok")
+	default:
+		writeMessage(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func renderCheckoutKey(k *CheckoutKey) map[string]interface{} {
+	return map[string]interface{}{
+		"public_key":  k.PublicKey,
+		"type":        k.Type,
+		"fingerprint": k.Fingerprint,
+		"preferred":   k.Preferred,
+		"time":        nil,
+	}
+}
+
+func (s *Server) serveCheckoutKeys(w http.ResponseWriter, r *http.Request, p *Project, rest []string) {
+	if len(rest) == 0 && r.Method == "GET" {
+		keys := make([]map[string]interface{}, len(p.CheckoutKeys))
+		for i, k := range p.CheckoutKeys {
+			keys[i] = renderCheckoutKey(k)
+		}
+		writeJSON(w, http.StatusOK, keys)
+		return
+	}
+	if len(rest) == 0 && r.Method == "POST" {
+		var body struct {
+			Type string `json:"type"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Invalid JSON body")
+			return
+		}
+		if body.Type != "deploy-key" && body.Type != "github-user-key" {
+			writeMessage(w, http.StatusBadRequest, "Invalid key type")
+			return
+		}
+		// The keys aren't real, but they're different for every key.
+		n := len(p.CheckoutKeys) + 1
+		k := &CheckoutKey{
+			PublicKey:   fmt.Sprintf("ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ%04d", n),
+			Type:        body.Type,
+			Fingerprint: fmt.Sprintf("c9:0b:1c:4f:d5:65:56:b9:ad:88:f9:81:2b:37:%02x:%02x", n/256, n%256),
+			Preferred:   len(p.CheckoutKeys) == 0,
+		}
+		p.CheckoutKeys = append(p.CheckoutKeys, k)
+		writeJSON(w, http.StatusCreated, renderCheckoutKey(k))
+		return
+	}
+	for i, k := range p.CheckoutKeys {
+		if len(rest) != 1 || k.Fingerprint != rest[0] {
+			continue
+		}
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, renderCheckoutKey(k))
+		case "DELETE":
+			p.CheckoutKeys = append(p.CheckoutKeys[:i], p.CheckoutKeys[i+1:]...)
+			writeMessage(w, http.StatusOK, "OK")
+		default:
+			writeMessage(w, http.StatusNotFound, "Not found")
+		}
+		return
+	}
+	writeMessage(w, http.StatusNotFound, "Checkout key not found")
+}
+
+func (s *Server) serveAddSSHKey(w http.ResponseWriter, r *http.Request, p *Project) {
+	var body struct {
+		Hostname   string `json:"hostname"`
+		PrivateKey string `json:"private_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if !strings.HasPrefix(body.PrivateKey, "-----BEGIN ") {
+		writeMessage(w, http.StatusBadRequest, "Invalid private key")
+		return
+	}
+	p.SSHKeys = append(p.SSHKeys, SSHKey{Hostname: body.Hostname, PrivateKey: body.PrivateKey})
+	w.WriteHeader(http.StatusOK)
+}
+
+// projectBuild is a build along with the project it belongs to.
+type projectBuild struct {
+	p *Project
+	b *Build
+}
+
+// serveTree lists the builds for branch, most recent first, or for every
+// branch if branch is empty.
+func (s *Server) serveTree(w http.ResponseWriter, r *http.Request, p *Project, branch string) {
+	builds := make([]projectBuild, 0)
+	for i := len(p.Builds) - 1; i >= 0; i-- {
+		if branch == "" || p.Builds[i].Branch == branch {
+			builds = append(builds, projectBuild{p, p.Builds[i]})
+		}
+	}
+	s.serveBuilds(w, r, builds)
+}
+
+// serveRecent lists the builds for every project, most recently queued first.
+func (s *Server) serveRecent(w http.ResponseWriter, r *http.Request) {
+	builds := make([]projectBuild, 0)
+	for _, p := range s.projects {
+		for _, b := range p.Builds {
+			builds = append(builds, projectBuild{p, b})
+		}
+	}
+	sort.Slice(builds, func(i, j int) bool {
+		if !builds[i].b.QueuedAt.Equal(builds[j].b.QueuedAt) {
+			return builds[i].b.QueuedAt.After(builds[j].b.QueuedAt)
+		}
+		if builds[i].p != builds[j].p {
+			return projectKey(builds[i].p.Username, builds[i].p.RepoName) < projectKey(builds[j].p.Username, builds[j].p.RepoName)
+		}
+		return builds[i].b.BuildNum > builds[j].b.BuildNum
+	})
+	s.serveBuilds(w, r, builds)
+}
+
+// serveProjects lists every followed project, with a summary of the builds on
+// each branch.
+func (s *Server) serveProjects(w http.ResponseWriter) {
+	keys := make([]string, 0, len(s.projects))
+	for key, p := range s.projects {
+		if p.Following {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	resp := make([]map[string]interface{}, len(keys))
+	for i, key := range keys {
+		p := s.projects[key]
+		host := "github.com"
+		if p.VCSType == "bitbucket" {
+			host = "bitbucket.org"
+		}
+		resp[i] = map[string]interface{}{
+			"vcs_url":        fmt.Sprintf("https://%s/%s/%s", host, p.Username, p.RepoName),
+			"vcs_type":       p.VCSType,
+			"username":       p.Username,
+			"reponame":       p.RepoName,
+			"following":      true,
+			"default_branch": p.DefaultBranch,
+			"branches":       branchSummaries(p),
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func branchBuild(b *Build) map[string]interface{} {
+	return map[string]interface{}{
+		"build_num":    b.BuildNum,
+		"status":       b.Status,
+		"outcome":      nil,
+		"vcs_revision": b.VCSRevision,
+		"pushed_at":    nullTime(b.QueuedAt),
+		"added_at":     nullTime(b.QueuedAt),
+	}
+}
+
+// branchSummaries summarizes the builds on each branch the way /projects
+// does, with escaped branch names as keys.
+func branchSummaries(p *Project) map[string]interface{} {
+	branches := make(map[string]interface{})
+	for i := len(p.Builds) - 1; i >= 0; i-- {
+		b := p.Builds[i]
+		key := url.PathEscape(b.Branch)
+		summary, ok := branches[key].(map[string]interface{})
+		if !ok {
+			summary = map[string]interface{}{
+				"pusher_logins":    []string{p.Username},
+				"last_success":     nil,
+				"last_non_success": nil,
+				"recent_builds":    []interface{}{},
+				"running_builds":   []interface{}{},
+			}
+			branches[key] = summary
+		}
+		switch {
+		case b.Status == "running":
+			summary["running_builds"] = append(summary["running_builds"].([]interface{}), branchBuild(b))
+		case isTerminal(b.Status):
+			summary["recent_builds"] = append(summary["recent_builds"].([]interface{}), branchBuild(b))
+			field := "last_non_success"
+			if b.Status == "success" || b.Status == "fixed" {
+				field = "last_success"
+			}
+			if summary[field] == nil {
+				summary[field] = branchBuild(b)
+			}
+		}
+	}
+	return branches
+}
+
+// serveBuilds writes a page of builds, using the limit, offset and filter
+// query parameters.
+func (s *Server) serveBuilds(w http.ResponseWriter, r *http.Request, builds []projectBuild) {
+	query := r.URL.Query()
+	limit := 30
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 100 {
+			writeMessage(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if o := query.Get("offset"); o != "" {
+		n, err := strconv.Atoi(o)
+		if err != nil || n < 0 {
+			writeMessage(w, http.StatusBadRequest, "offset must be a positive number")
+			return
+		}
+		offset = n
+	}
+	filter := query.Get("filter")
+	matched := builds[:0]
+	for _, pb := range builds {
+		if matchesFilter(filter, pb.b.Status) {
+			matched = append(matched, pb)
+		}
+	}
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	matched = matched[offset:]
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	resp := make([]map[string]interface{}, len(matched))
+	for i, pb := range matched {
+		resp[i] = s.renderBuild(pb.p, pb.b, false)
+	}
+	writeJSON(w, http.StatusOK, resp)
+	for _, pb := range matched {
+		advance(pb.b)
+	}
+}
+
+func (s *Server) serveOutput(w http.ResponseWriter, b *Build, stepStr, indexStr string) {
+	step, err := strconv.Atoi(stepStr)
+	if err != nil || step < 0 || step >= len(b.Steps) {
+		writeMessage(w, http.StatusNotFound, "Step not found")
+		return
+	}
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 || index >= len(b.Steps[step].Actions) {
+		writeMessage(w, http.StatusNotFound, "Action not found")
+		return
+	}
+	a := b.Steps[step].Actions[index]
+	writeJSON(w, http.StatusOK, []map[string]interface{}{{
+		"message": a.Output,
+		"time":    b.QueuedAt.Format(time.RFC3339),
+		"type":    "out",
+	}})
+}
+
+func (s *Server) serveTests(w http.ResponseWriter, b *Build) {
+	tests := make([]map[string]interface{}, len(b.Tests))
+	for i, tr := range b.Tests {
+		var message interface{}
+		if tr.Message != "" {
+			message = tr.Message
+		}
+		tests[i] = map[string]interface{}{
+			"name":        tr.Name,
+			"classname":   tr.Classname,
+			"file":        tr.File,
+			"result":      tr.Result,
+			"run_time":    tr.RunTime.Seconds(),
+			"message":     message,
+			"source":      "go-test",
+			"source_type": "test_results",
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tests": tests})
+}
+
+func (s *Server) artifactURL(p *Project, b *Build, a Artifact) string {
+	return fmt.Sprintf("%s/artifacts/%s/%s/%d/%d/%s", s.URL, p.Username, p.RepoName, b.BuildNum, a.NodeIndex, strings.TrimPrefix(a.Path, "/"))
+}
+
+func (s *Server) serveArtifacts(w http.ResponseWriter, p *Project, b *Build) {
+	resp := make([]map[string]interface{}, len(b.Artifacts))
+	for i, a := range b.Artifacts {
+		resp[i] = map[string]interface{}{
+			"path":        a.Path,
+			"pretty_path": "$CIRCLE_ARTIFACTS/" + strings.TrimPrefix(a.Path, "/"),
+			"node_index":  a.NodeIndex,
+			"url":         s.artifactURL(p, b, a),
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) serveArtifact(w http.ResponseWriter, r *http.Request) {
+	// /artifacts/:org/:repo/:build/:node/:path...
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/artifacts/"), "/", 5)
+	if len(parts) != 5 {
+		http.NotFound(w, r)
+		return
+	}
+	buildNum, _ := strconv.Atoi(parts[2])
+	node, _ := strconv.Atoi(parts[3])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.build(parts[0], parts[1], buildNum)
+	if b == nil {
+		http.NotFound(w, r)
+		return
+	}
+	for _, a := range b.Artifacts {
+		if a.NodeIndex == node && strings.TrimPrefix(a.Path, "/") == parts[4] {
+			w.Write([]byte(a.Body))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func (s *Server) previous(p *Project, b *Build) interface{} {
+	if b.Previous == 0 {
+		return nil
+	}
+	for _, pb := range p.Builds {
+		if pb.BuildNum == b.Previous {
+			var millis int64
+			if !pb.StopTime.IsZero() && !pb.StartTime.IsZero() {
+				millis = int64(pb.StopTime.Sub(pb.StartTime) / time.Millisecond)
+			}
+			return map[string]interface{}{
+				"build_num":         pb.BuildNum,
+				"status":            pb.Status,
+				"build_time_millis": millis,
+			}
+		}
+	}
+	return nil
+}
+
+// renderBuild returns the JSON representation of a build. Steps are only
+// included in the detailed view, as with the real API.
+func (s *Server) renderBuild(p *Project, b *Build, detailed bool) map[string]interface{} {
+	m := map[string]interface{}{
+		"build_num":       b.BuildNum,
+		"build_url":       fmt.Sprintf("%s/gh/%s/%s/%d", s.URL, p.Username, p.RepoName, b.BuildNum),
+		"compare":         nil,
+		"branch":          b.Branch,
+		"previous":        s.previous(p, b),
+		"queued_at":       nullTime(b.QueuedAt),
+		"usage_queued_at": nullTime(b.QueuedAt),
+		"start_time":      nullTime(b.StartTime),
+		"stop_time":       nullTime(b.StopTime),
+		"reponame":        p.RepoName,
+		"username":        p.Username,
+		"status":          b.Status,
+		"lifecycle":       lifecycle(b.Status),
+		"outcome":         outcome(b.Status),
+		"vcs_revision":    b.VCSRevision,
+		"vcs_type":        p.VCSType,
+		"parallel":        b.Parallel,
+	}
+	if !detailed {
+		return m
+	}
+	m["previous_successful_build"] = nil
+	m["build_parameters"] = b.BuildParameters
+	steps := make([]map[string]interface{}, len(b.Steps))
+	for i, step := range b.Steps {
+		actions := make([]map[string]interface{}, len(step.Actions))
+		for j, a := range step.Actions {
+			actions[j] = map[string]interface{}{
+				"name":            a.Name,
+				"status":          a.Status,
+				"run_time_millis": int64(a.Runtime / time.Millisecond),
+				"index":           j,
+				"step":            i,
+				"has_output":      a.Output != "",
+				"output_url":      fmt.Sprintf("%s/v1.1/project/%s/%s/%s/%d/output/%d/%d", s.URL, p.VCSType, p.Username, p.RepoName, b.BuildNum, i, j),
+			}
+		}
+		steps[i] = map[string]interface{}{
+			"name":    step.Name,
+			"actions": actions,
+		}
+	}
+	m["steps"] = steps
+	return m
+}
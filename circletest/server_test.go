@@ -0,0 +1,143 @@
+package circletest_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	circle "github.com/Shyp/go-circle"
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func newClient(s *circletest.Server) *circle.Client {
+	return &circle.Client{BaseURL: s.URL, Tokens: circle.StaticToken("token")}
+}
+
+func TestTreeAndScript(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "success"})
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master"})
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "other"})
+	s.Script("Shyp", "go-circle", b.BuildNum, "running", "failed")
+	c := newClient(s)
+	ctx := context.Background()
+	for _, want := range []string{"queued", "running", "failed", "failed"} {
+		cr, err := c.GetTree(ctx, "Shyp", "go-circle", "master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(*cr) != 2 {
+			t.Fatalf("expected 2 builds on master, got %d", len(*cr))
+		}
+		if string((*cr)[0].Status) != want {
+			t.Errorf("expected status %s, got %s", want, (*cr)[0].Status)
+		}
+		if (*cr)[0].Previous.BuildNum != 1 {
+			t.Errorf("expected previous build to be 1, got %d", (*cr)[0].Previous.BuildNum)
+		}
+	}
+}
+
+func TestCancelRetryFollow(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Status: "running"})
+	c := newClient(s)
+	ctx := context.Background()
+	if _, err := c.CancelBuild(ctx, "Shyp", "go-circle", b.BuildNum); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Build("Shyp", "go-circle", b.BuildNum); got.Status != "canceled" {
+		t.Errorf("expected build to be canceled, was %s", got.Status)
+	}
+	if err := c.Rebuild(ctx, &circle.TreeBuild{BuildNum: b.BuildNum, Username: "Shyp", RepoName: "go-circle", VCSType: "github"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Build("Shyp", "go-circle", b.BuildNum+1); !ok {
+		t.Error("expected retry to create a new build")
+	}
+	if err := c.Enable(ctx, "github.com", "Shyp", "go-circle"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Following("Shyp", "go-circle") {
+		t.Error("expected project to be followed")
+	}
+	if _, err := c.GetBuild(ctx, "Shyp", "go-circle", 99); err == nil {
+		t.Error("expected an error fetching unknown build, got nil")
+	}
+}
+
+func TestArtifactsAndOutput(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{
+		Status:   "failed",
+		Parallel: 2,
+		Steps: []circletest.Step{{Name: "make test", Actions: []circletest.Action{
+			{Status: "success"},
+			{Status: "failed", Output: "FAIL"},
+		}}},
+		Artifacts: []circletest.Artifact{{Path: "coverage.out", NodeIndex: 1, Body: "mode: set"}},
+	})
+	c := newClient(s)
+	ctx := context.Background()
+	cb, err := c.GetBuild(ctx, "Shyp", "go-circle", b.BuildNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts, err := c.FailureTexts(ctx, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 1 || texts[0] != "FAIL\n" {
+		t.Errorf("bad failure texts: %q", texts)
+	}
+	arts, err := c.GetArtifactsForBuild(ctx, "Shyp", "go-circle", b.BuildNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(arts) != 1 {
+		t.Fatalf("expected 1 artifact, got %d", len(arts))
+	}
+	dir, err := ioutil.TempDir("", "circletest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := c.DownloadArtifact(ctx, arts[0], dir, "Shyp"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "1.coverage.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "mode: set" {
+		t.Errorf("bad artifact contents: %q", data)
+	}
+}
+
+func TestToken(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.Token = "right"
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{})
+	c := &circle.Client{BaseURL: s.URL, Tokens: circle.StaticToken("wrong")}
+	if _, err := c.GetTree(context.Background(), "Shyp", "go-circle", "master"); err == nil {
+		t.Fatal("expected an error with the wrong token, got nil")
+	}
+}
+
+func TestProjectPathNotFound(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{})
+	c := newClient(s)
+	err := c.Do(context.Background(), "Shyp", "DELETE", "/v1.1/project/github/Shyp/go-circle", nil, nil)
+	if !errors.Is(err, circle.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
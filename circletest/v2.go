@@ -0,0 +1,481 @@
+package circletest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pipeline is a pipeline in the fake server's v2 model. Fields left empty when
+// the pipeline is added are filled in with defaults by AddPipeline.
+type Pipeline struct {
+	ID       string
+	Number   int
+	Branch   string
+	Revision string
+	State    string
+	// Errors are the messages of the pipeline's errors, for pipelines in
+	// the "errored" state.
+	Errors    []string
+	CreatedAt time.Time
+	Workflows []*Workflow
+}
+
+// Workflow is a workflow in a Pipeline.
+type Workflow struct {
+	ID   string
+	Name string
+	// Status, if set, is used instead of the status worked out from the
+	// status of the jobs.
+	Status string
+	Jobs   []*Job
+}
+
+// Job is a job in a Workflow.
+type Job struct {
+	ID   string
+	Name string
+	// Type is "build", the default, or "approval".
+	Type string
+	// BuildNum is the build in the same project that runs the job. The job
+	// has the build's status, and listing the jobs in a workflow advances
+	// the build's script like fetching the build does. It's zero for
+	// approval jobs and jobs that haven't started.
+	BuildNum int
+	// Status is the status of a job that doesn't have a build. It defaults
+	// to "on_hold" for approval jobs and "blocked" for other jobs.
+	Status string
+	// Dependencies are the names of the jobs in the workflow that must
+	// finish before this one starts.
+	Dependencies []string
+	ApprovedBy   string
+}
+
+// AddPipeline adds pl to the project identified by org and repo, creating a
+// GitHub project if none exists, and returns pl. IDs that are empty are
+// generated, and the branch defaults to "master".
+func (s *Server) AddPipeline(org, repo string, pl *Pipeline) *Pipeline {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.addProject("github", org, repo)
+	s.ids++
+	if pl.ID == "" {
+		pl.ID = fakeUUID(s.ids)
+	}
+	if pl.Number == 0 {
+		pl.Number = len(p.Pipelines) + 1
+	}
+	if pl.Branch == "" {
+		pl.Branch = "master"
+	}
+	if pl.State == "" {
+		pl.State = "created"
+	}
+	if pl.CreatedAt.IsZero() {
+		pl.CreatedAt = time.Now().UTC()
+	}
+	for _, wf := range pl.Workflows {
+		if wf.ID == "" {
+			s.ids++
+			wf.ID = fakeUUID(s.ids)
+		}
+		for _, j := range wf.Jobs {
+			if j.ID == "" {
+				s.ids++
+				j.ID = fakeUUID(s.ids)
+			}
+			if j.Type == "" {
+				j.Type = "build"
+			}
+		}
+	}
+	p.Pipelines = append(p.Pipelines, pl)
+	return pl
+}
+
+func fakeUUID(n int) string {
+	return fmt.Sprintf("%08x-0000-4000-8000-%012x", n, n)
+}
+
+// projectSlug returns the v2 identifier for p, like "gh/Shyp/go-circle".
+func projectSlug(p *Project) string {
+	vcs := p.VCSType
+	switch vcs {
+	case "github":
+		vcs = "gh"
+	case "bitbucket":
+		vcs = "bb"
+	}
+	return vcs + "/" + p.Username + "/" + p.RepoName
+}
+
+func (s *Server) pageSize() int {
+	if s.PageSize <= 0 {
+		return 20
+	}
+	return s.PageSize
+}
+
+// paginate returns the page of n items selected by the page-token parameter,
+// which is the offset of the first item, and the token for the next page.
+func (s *Server) paginate(r *http.Request, n int) (start, end int, next interface{}) {
+	start, _ = strconv.Atoi(r.URL.Query().Get("page-token"))
+	if start < 0 || start > n {
+		start = n
+	}
+	end = start + s.pageSize()
+	if end >= n {
+		return start, n, nil
+	}
+	return start, end, strconv.Itoa(end)
+}
+
+func (s *Server) findPipeline(id string) (*Project, *Pipeline) {
+	for _, p := range s.projects {
+		for _, pl := range p.Pipelines {
+			if pl.ID == id {
+				return p, pl
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) findWorkflow(id string) (*Project, *Pipeline, *Workflow) {
+	for _, p := range s.projects {
+		for _, pl := range p.Pipelines {
+			for _, wf := range pl.Workflows {
+				if wf.ID == id {
+					return p, pl, wf
+				}
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+func (s *Server) projectForSlug(vcs, org, repo string) *Project {
+	p, ok := s.projects[projectKey(org, repo)]
+	if !ok || projectSlug(p) != vcs+"/"+p.Username+"/"+p.RepoName {
+		return nil
+	}
+	return p
+}
+
+// jobStatus returns the v2 status of a job, from its build if it has one.
+func jobStatus(p *Project, j *Job) string {
+	if j.BuildNum != 0 {
+		for _, b := range p.Builds {
+			if b.BuildNum != j.BuildNum {
+				continue
+			}
+			switch b.Status {
+			case "fixed":
+				return "success"
+			case "no_tests":
+				return "failed"
+			case "scheduled":
+				return "queued"
+			}
+			return b.Status
+		}
+	}
+	if j.Status != "" {
+		return j.Status
+	}
+	if j.Type == "approval" {
+		return "on_hold"
+	}
+	return "blocked"
+}
+
+// workflowStatus works out the status of a workflow from its jobs, the way
+// CircleCI does.
+func workflowStatus(p *Project, wf *Workflow) string {
+	if wf.Status != "" {
+		return wf.Status
+	}
+	var failed, active, hold, canceled bool
+	for _, j := range wf.Jobs {
+		switch jobStatus(p, j) {
+		case "failed", "timedout", "infrastructure_fail", "terminated-unknown":
+			failed = true
+		case "running", "queued", "not_running":
+			active = true
+		case "on_hold":
+			hold = true
+		case "canceled":
+			canceled = true
+		}
+	}
+	switch {
+	case failed && active:
+		return "failing"
+	case failed:
+		return "failed"
+	case active:
+		return "running"
+	case hold:
+		return "on_hold"
+	case canceled:
+		return "canceled"
+	}
+	return "success"
+}
+
+func (s *Server) serveV2(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i := range parts {
+		if part, err := url.PathUnescape(parts[i]); err == nil {
+			parts[i] = part
+		}
+	}
+	if len(parts) == 5 && parts[1] == "workflow" && parts[3] == "approve" && r.Method == "POST" {
+		s.serveApprove(w, parts[2], parts[4])
+		return
+	}
+	if r.Method != "GET" {
+		writeMessage(w, http.StatusNotFound, "Not found")
+		return
+	}
+	switch {
+	case len(parts) == 6 && parts[1] == "project" && parts[5] == "pipeline":
+		p := s.projectForSlug(parts[2], parts[3], parts[4])
+		if p == nil {
+			writeMessage(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		s.servePipelines(w, r, p)
+	case len(parts) == 7 && parts[1] == "project" && parts[5] == "job":
+		p := s.projectForSlug(parts[2], parts[3], parts[4])
+		num, err := strconv.Atoi(parts[6])
+		if p == nil || err != nil {
+			writeMessage(w, http.StatusNotFound, "Job not found")
+			return
+		}
+		s.serveJobDetails(w, p, num)
+	case len(parts) == 3 && parts[1] == "pipeline":
+		p, pl := s.findPipeline(parts[2])
+		if pl == nil {
+			writeMessage(w, http.StatusNotFound, "Pipeline not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.renderPipeline(p, pl))
+	case len(parts) == 4 && parts[1] == "pipeline" && parts[3] == "workflow":
+		p, pl := s.findPipeline(parts[2])
+		if pl == nil {
+			writeMessage(w, http.StatusNotFound, "Pipeline not found")
+			return
+		}
+		start, end, next := s.paginate(r, len(pl.Workflows))
+		items := make([]map[string]interface{}, 0, end-start)
+		for _, wf := range pl.Workflows[start:end] {
+			items = append(items, renderWorkflow(p, pl, wf))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "next_page_token": next})
+	case len(parts) == 3 && parts[1] == "workflow":
+		p, pl, wf := s.findWorkflow(parts[2])
+		if wf == nil {
+			writeMessage(w, http.StatusNotFound, "Workflow not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, renderWorkflow(p, pl, wf))
+	case len(parts) == 4 && parts[1] == "workflow" && parts[3] == "job":
+		p, _, wf := s.findWorkflow(parts[2])
+		if wf == nil {
+			writeMessage(w, http.StatusNotFound, "Workflow not found")
+			return
+		}
+		start, end, next := s.paginate(r, len(wf.Jobs))
+		items := make([]map[string]interface{}, 0, end-start)
+		for _, j := range wf.Jobs[start:end] {
+			if b := s.build(p.Username, p.RepoName, j.BuildNum); b != nil {
+				advance(b)
+			}
+			items = append(items, s.renderJob(p, wf, j))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "next_page_token": next})
+	default:
+		writeMessage(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// serveApprove approves the approval job in the workflow whose approval
+// request ID is requestID, which is the ID of the job.
+func (s *Server) serveApprove(w http.ResponseWriter, workflowID, requestID string) {
+	p, _, wf := s.findWorkflow(workflowID)
+	if wf == nil {
+		writeMessage(w, http.StatusNotFound, "Workflow not found")
+		return
+	}
+	for _, j := range wf.Jobs {
+		if j.Type != "approval" || j.ID != requestID {
+			continue
+		}
+		if jobStatus(p, j) != "on_hold" {
+			writeMessage(w, http.StatusBadRequest, "Job is not on hold")
+			return
+		}
+		j.Status = "success"
+		j.ApprovedBy = fakeUUID(0)
+		writeMessage(w, http.StatusAccepted, "Accepted.")
+		return
+	}
+	writeMessage(w, http.StatusNotFound, "Approval request not found")
+}
+
+func (s *Server) servePipelines(w http.ResponseWriter, r *http.Request, p *Project) {
+	branch := r.URL.Query().Get("branch")
+	var pipelines []*Pipeline
+	for i := len(p.Pipelines) - 1; i >= 0; i-- {
+		if branch == "" || p.Pipelines[i].Branch == branch {
+			pipelines = append(pipelines, p.Pipelines[i])
+		}
+	}
+	start, end, next := s.paginate(r, len(pipelines))
+	items := make([]map[string]interface{}, 0, end-start)
+	for _, pl := range pipelines[start:end] {
+		items = append(items, s.renderPipeline(p, pl))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "next_page_token": next})
+}
+
+func (s *Server) renderPipeline(p *Project, pl *Pipeline) map[string]interface{} {
+	errors := make([]map[string]interface{}, len(pl.Errors))
+	for i, msg := range pl.Errors {
+		errors[i] = map[string]interface{}{"type": "config", "message": msg}
+	}
+	return map[string]interface{}{
+		"id":           pl.ID,
+		"errors":       errors,
+		"project_slug": projectSlug(p),
+		"number":       pl.Number,
+		"state":        pl.State,
+		"created_at":   pl.CreatedAt.Format(time.RFC3339),
+		"updated_at":   pl.CreatedAt.Format(time.RFC3339),
+		"trigger": map[string]interface{}{
+			"type":        "webhook",
+			"received_at": pl.CreatedAt.Format(time.RFC3339),
+			"actor":       map[string]interface{}{"login": p.Username, "avatar_url": nil},
+		},
+		"vcs": map[string]interface{}{
+			"provider_name":         "GitHub",
+			"target_repository_url": fmt.Sprintf("https://github.com/%s/%s", p.Username, p.RepoName),
+			"origin_repository_url": fmt.Sprintf("https://github.com/%s/%s", p.Username, p.RepoName),
+			"branch":                pl.Branch,
+			"revision":              pl.Revision,
+		},
+	}
+}
+
+func renderWorkflow(p *Project, pl *Pipeline, wf *Workflow) map[string]interface{} {
+	status := workflowStatus(p, wf)
+	var stoppedAt interface{}
+	switch status {
+	case "running", "failing", "on_hold":
+	default:
+		stoppedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"id":              wf.ID,
+		"name":            wf.Name,
+		"pipeline_id":     pl.ID,
+		"pipeline_number": pl.Number,
+		"project_slug":    projectSlug(p),
+		"status":          status,
+		"started_by":      fakeUUID(0),
+		"canceled_by":     nil,
+		"errored_by":      nil,
+		"tag":             nil,
+		"created_at":      pl.CreatedAt.Format(time.RFC3339),
+		"stopped_at":      stoppedAt,
+	}
+}
+
+func (s *Server) renderJob(p *Project, wf *Workflow, j *Job) map[string]interface{} {
+	deps := []string{}
+	for _, name := range j.Dependencies {
+		for _, dep := range wf.Jobs {
+			if dep.Name == name {
+				deps = append(deps, dep.ID)
+			}
+		}
+	}
+	m := map[string]interface{}{
+		"id":           j.ID,
+		"name":         j.Name,
+		"type":         j.Type,
+		"project_slug": projectSlug(p),
+		"status":       jobStatus(p, j),
+		"dependencies": deps,
+		"job_number":   nil,
+		"started_at":   nil,
+		"stopped_at":   nil,
+	}
+	if b := s.build(p.Username, p.RepoName, j.BuildNum); b != nil {
+		m["job_number"] = b.BuildNum
+		m["started_at"] = nullTime(b.StartTime)
+		m["stopped_at"] = nullTime(b.StopTime)
+	}
+	if j.Type == "approval" {
+		m["approval_request_id"] = j.ID
+		if j.ApprovedBy != "" {
+			m["approved_by"] = j.ApprovedBy
+		}
+	}
+	return m
+}
+
+func (s *Server) serveJobDetails(w http.ResponseWriter, p *Project, num int) {
+	b := s.build(p.Username, p.RepoName, num)
+	if b == nil {
+		writeMessage(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	name := "build"
+	var pipelineID, workflowID, workflowName interface{}
+	for _, pl := range p.Pipelines {
+		for _, wf := range pl.Workflows {
+			for _, j := range wf.Jobs {
+				if j.BuildNum == num {
+					name, pipelineID, workflowID, workflowName = j.Name, pl.ID, wf.ID, wf.Name
+				}
+			}
+		}
+	}
+	runs := make([]map[string]interface{}, b.Parallel)
+	for i := range runs {
+		runs[i] = map[string]interface{}{"index": i, "status": b.Status}
+	}
+	var duration interface{}
+	if !b.StartTime.IsZero() && !b.StopTime.IsZero() {
+		duration = int64(b.StopTime.Sub(b.StartTime) / time.Millisecond)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"number":        b.BuildNum,
+		"name":          name,
+		"status":        b.Status,
+		"web_url":       fmt.Sprintf("%s/gh/%s/%s/%d", s.URL, p.Username, p.RepoName, b.BuildNum),
+		"parallelism":   b.Parallel,
+		"parallel_runs": runs,
+		"project": map[string]interface{}{
+			"slug":         projectSlug(p),
+			"name":         p.RepoName,
+			"external_url": fmt.Sprintf("https://github.com/%s/%s", p.Username, p.RepoName),
+		},
+		"pipeline":        map[string]interface{}{"id": pipelineID},
+		"latest_workflow": map[string]interface{}{"id": workflowID, "name": workflowName},
+		"executor":        map[string]interface{}{"type": "docker", "resource_class": "medium"},
+		"contexts":        []interface{}{},
+		"organization":    map[string]interface{}{"name": p.Username},
+		"messages":        []interface{}{},
+		"duration":        duration,
+		"created_at":      b.QueuedAt.Format(time.RFC3339),
+		"queued_at":       b.QueuedAt.Format(time.RFC3339),
+		"started_at":      nullTime(b.StartTime),
+		"stopped_at":      nullTime(b.StopTime),
+	})
+}
@@ -0,0 +1,43 @@
+package circlev2
+
+import (
+	"context"
+	"net/url"
+)
+
+// IsPendingApproval reports whether j is an approval job waiting for someone
+// to approve it.
+func (j *Job) IsPendingApproval() bool {
+	return j.Type == JobTypeApproval && j.Status == JobOnHold
+}
+
+// ListApprovalJobs returns the approval jobs in a workflow that are waiting to
+// be approved.
+func (c *Client) ListApprovalJobs(ctx context.Context, org, workflowID string) ([]*Job, error) {
+	jobs, err := c.ListJobs(ctx, org, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Job
+	for _, j := range jobs {
+		if j.IsPendingApproval() {
+			pending = append(pending, j)
+		}
+	}
+	return pending, nil
+}
+
+// ApproveJob approves an approval job in a workflow, which lets the jobs that
+// depend on it start. approvalRequestID is the job's ApprovalRequestID.
+func (c *Client) ApproveJob(ctx context.Context, org, workflowID, approvalRequestID string) error {
+	path := "/v2/workflow/" + url.PathEscape(workflowID) + "/approve/" + url.PathEscape(approvalRequestID)
+	return c.base().Do(ctx, org, "POST", path, nil, nil)
+}
+
+func ListApprovalJobs(ctx context.Context, org, workflowID string) ([]*Job, error) {
+	return DefaultClient.ListApprovalJobs(ctx, org, workflowID)
+}
+
+func ApproveJob(ctx context.Context, org, workflowID, approvalRequestID string) error {
+	return DefaultClient.ApproveJob(ctx, org, workflowID, approvalRequestID)
+}
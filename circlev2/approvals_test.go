@@ -0,0 +1,65 @@
+package circlev2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	circle "github.com/Shyp/go-circle"
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func TestApproveJob(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	deploy := &circletest.Workflow{Name: "deploy", Jobs: []*circletest.Job{
+		{Name: "hold-staging", Type: "approval"},
+		{Name: "hold-prod", Type: "approval", Status: "success", ApprovedBy: "someone"},
+		{Name: "deploy", Dependencies: []string{"hold-staging"}},
+	}}
+	addPipeline(s, "master", []string{"success"}, deploy)
+	c := newTestClient(s)
+	ctx := context.Background()
+
+	wf, err := c.GetWorkflow(ctx, "Shyp", deploy.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wf.Status != WorkflowOnHold {
+		t.Errorf("expected the workflow to be on hold, got %s", wf.Status)
+	}
+	jobs, err := c.ListApprovalJobs(ctx, "Shyp", deploy.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Name != "hold-staging" || jobs[0].ApprovalRequestID == "" {
+		t.Fatalf("expected only hold-staging to be pending, got %v", jobs)
+	}
+
+	if err := c.ApproveJob(ctx, "Shyp", deploy.ID, jobs[0].ApprovalRequestID); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err = c.ListJobs(ctx, "Shyp", deploy.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobs[0].Status != JobSuccess || jobs[0].ApprovedBy == "" {
+		t.Errorf("expected hold-staging to be approved, got %#v", jobs[0])
+	}
+	pending, err := c.ListApprovalJobs(ctx, "Shyp", deploy.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected no pending approvals, got %v", pending)
+	}
+
+	err = c.ApproveJob(ctx, "Shyp", deploy.ID, jobs[0].ApprovalRequestID)
+	var cerr *circle.Error
+	if !errors.As(err, &cerr) || cerr.StatusCode != 400 {
+		t.Errorf("expected approving twice to fail with a 400, got %v", err)
+	}
+	if err := c.ApproveJob(ctx, "Shyp", deploy.ID, "unknown"); !errors.Is(err, circle.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
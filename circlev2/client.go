@@ -0,0 +1,68 @@
+// Package circlev2 talks to version 2 of the CircleCI API, which describes
+// builds in terms of pipelines, workflows and jobs.
+//
+// A push to a branch starts a pipeline. The pipeline runs one or more
+// workflows, and each workflow runs a graph of jobs. Jobs of type "build" are
+// the same thing as builds in the v1 API, and their job number is the v1
+// build number.
+//
+// Requests are made with a circle.Client, so they use the same API tokens,
+// retries, logging and cache as the circle package.
+package circlev2
+
+import (
+	"fmt"
+	"net/url"
+
+	circle "github.com/Shyp/go-circle"
+)
+
+// Client makes requests to the v2 API.
+type Client struct {
+	// Base sends the requests. If nil, circle.DefaultClient is used.
+	Base *circle.Client
+}
+
+// DefaultClient is used by the package level functions like ListPipelines.
+var DefaultClient = new(Client)
+
+func (c *Client) base() *circle.Client {
+	if c.Base == nil {
+		return circle.DefaultClient
+	}
+	return c.Base
+}
+
+// ProjectSlug returns the v2 identifier for a project, for example
+// "gh/Shyp/go-circle". vcsType is "github" or "bitbucket".
+func ProjectSlug(vcsType, org, project string) string {
+	switch vcsType {
+	case "github":
+		vcsType = "gh"
+	case "bitbucket":
+		vcsType = "bb"
+	}
+	return vcsType + "/" + url.PathEscape(org) + "/" + url.PathEscape(project)
+}
+
+// withPageToken adds a page-token parameter to path, if token is set.
+func withPageToken(path string, query url.Values, token string) string {
+	if token != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("page-token", token)
+	}
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// maxPages stops a list that keeps returning page tokens from going on
+// forever.
+const maxPages = 100
+
+func errTooManyPages(path string) error {
+	return fmt.Errorf("circlev2: %s returned more than %d pages", path, maxPages)
+}
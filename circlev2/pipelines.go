@@ -0,0 +1,140 @@
+package circlev2
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// PipelineOptions selects a page of pipelines. The zero value gets the first
+// page of pipelines on every branch.
+type PipelineOptions struct {
+	// Branch, if set, only returns pipelines for the branch.
+	Branch string
+	// PageToken is the NextPageToken from the previous page.
+	PageToken string
+}
+
+// PipelinePage is a page of pipelines, most recent first.
+type PipelinePage struct {
+	Items []*Pipeline `json:"items"`
+	// NextPageToken gets the next page when used as PipelineOptions.PageToken.
+	// It's empty on the last page.
+	NextPageToken string `json:"next_page_token"`
+}
+
+// ListPipelines returns a page of the project's pipelines, most recent first.
+// vcsType is "github" or "bitbucket". opts may be nil.
+func (c *Client) ListPipelines(ctx context.Context, vcsType, org, project string, opts *PipelineOptions) (*PipelinePage, error) {
+	if opts == nil {
+		opts = new(PipelineOptions)
+	}
+	query := url.Values{}
+	if opts.Branch != "" {
+		query.Set("branch", opts.Branch)
+	}
+	uri := withPageToken("/v2/project/"+ProjectSlug(vcsType, org, project)+"/pipeline", query, opts.PageToken)
+	pp := new(PipelinePage)
+	if err := c.base().Do(ctx, org, "GET", uri, nil, pp); err != nil {
+		return nil, err
+	}
+	return pp, nil
+}
+
+// GetPipeline returns the pipeline with the given ID, using the API token for
+// org.
+func (c *Client) GetPipeline(ctx context.Context, org, id string) (*Pipeline, error) {
+	p := new(Pipeline)
+	if err := c.base().Do(ctx, org, "GET", "/v2/pipeline/"+url.PathEscape(id), nil, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListWorkflows returns every workflow in a pipeline.
+func (c *Client) ListWorkflows(ctx context.Context, org, pipelineID string) ([]*Workflow, error) {
+	path := "/v2/pipeline/" + url.PathEscape(pipelineID) + "/workflow"
+	var workflows []*Workflow
+	token := ""
+	for i := 0; i < maxPages; i++ {
+		var resp struct {
+			Items         []*Workflow `json:"items"`
+			NextPageToken string      `json:"next_page_token"`
+		}
+		if err := c.base().Do(ctx, org, "GET", withPageToken(path, nil, token), nil, &resp); err != nil {
+			return nil, err
+		}
+		workflows = append(workflows, resp.Items...)
+		if resp.NextPageToken == "" {
+			return workflows, nil
+		}
+		token = resp.NextPageToken
+	}
+	return nil, errTooManyPages(path)
+}
+
+// GetWorkflow returns the workflow with the given ID.
+func (c *Client) GetWorkflow(ctx context.Context, org, id string) (*Workflow, error) {
+	w := new(Workflow)
+	if err := c.base().Do(ctx, org, "GET", "/v2/workflow/"+url.PathEscape(id), nil, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ListJobs returns every job in a workflow, including approval jobs.
+func (c *Client) ListJobs(ctx context.Context, org, workflowID string) ([]*Job, error) {
+	path := "/v2/workflow/" + url.PathEscape(workflowID) + "/job"
+	var jobs []*Job
+	token := ""
+	for i := 0; i < maxPages; i++ {
+		var resp struct {
+			Items         []*Job `json:"items"`
+			NextPageToken string `json:"next_page_token"`
+		}
+		if err := c.base().Do(ctx, org, "GET", withPageToken(path, nil, token), nil, &resp); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, resp.Items...)
+		if resp.NextPageToken == "" {
+			return jobs, nil
+		}
+		token = resp.NextPageToken
+	}
+	return nil, errTooManyPages(path)
+}
+
+// GetJob returns the details of the job with the given number, which is the
+// same as its v1 build number.
+func (c *Client) GetJob(ctx context.Context, vcsType, org, project string, jobNumber int) (*JobDetails, error) {
+	uri := fmt.Sprintf("/v2/project/%s/job/%d", ProjectSlug(vcsType, org, project), jobNumber)
+	jd := new(JobDetails)
+	if err := c.base().Do(ctx, org, "GET", uri, nil, jd); err != nil {
+		return nil, err
+	}
+	return jd, nil
+}
+
+func ListPipelines(ctx context.Context, vcsType, org, project string, opts *PipelineOptions) (*PipelinePage, error) {
+	return DefaultClient.ListPipelines(ctx, vcsType, org, project, opts)
+}
+
+func GetPipeline(ctx context.Context, org, id string) (*Pipeline, error) {
+	return DefaultClient.GetPipeline(ctx, org, id)
+}
+
+func ListWorkflows(ctx context.Context, org, pipelineID string) ([]*Workflow, error) {
+	return DefaultClient.ListWorkflows(ctx, org, pipelineID)
+}
+
+func GetWorkflow(ctx context.Context, org, id string) (*Workflow, error) {
+	return DefaultClient.GetWorkflow(ctx, org, id)
+}
+
+func ListJobs(ctx context.Context, org, workflowID string) ([]*Job, error) {
+	return DefaultClient.ListJobs(ctx, org, workflowID)
+}
+
+func GetJob(ctx context.Context, vcsType, org, project string, jobNumber int) (*JobDetails, error) {
+	return DefaultClient.GetJob(ctx, vcsType, org, project, jobNumber)
+}
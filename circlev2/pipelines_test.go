@@ -0,0 +1,158 @@
+package circlev2
+
+import (
+	"context"
+	"testing"
+
+	circle "github.com/Shyp/go-circle"
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func newTestClient(s *circletest.Server) *Client {
+	return &Client{Base: &circle.Client{
+		BaseURL: s.URL,
+		Tokens:  circle.StaticToken("token"),
+		Retry:   circle.NoRetries,
+	}}
+}
+
+// addPipeline adds a pipeline with a "build" workflow, followed by extra. The
+// build workflow has a job for each status: build, then test and lint, which
+// run after build.
+func addPipeline(s *circletest.Server, branch string, statuses []string, extra ...*circletest.Workflow) *circletest.Pipeline {
+	wf := &circletest.Workflow{Name: "build"}
+	names := []string{"build", "test", "lint"}
+	for i, status := range statuses {
+		b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: branch, Status: status})
+		j := &circletest.Job{Name: names[i], BuildNum: b.BuildNum}
+		if i > 0 {
+			j.Dependencies = []string{"build"}
+		}
+		wf.Jobs = append(wf.Jobs, j)
+	}
+	return s.AddPipeline("Shyp", "go-circle", &circletest.Pipeline{
+		Branch:    branch,
+		Revision:  "4e8f3c1",
+		Workflows: append([]*circletest.Workflow{wf}, extra...),
+	})
+}
+
+func TestProjectSlug(t *testing.T) {
+	if slug := ProjectSlug("github", "Shyp", "go-circle"); slug != "gh/Shyp/go-circle" {
+		t.Errorf("expected gh/Shyp/go-circle, got %s", slug)
+	}
+	if slug := ProjectSlug("bitbucket", "Shyp", "go-circle"); slug != "bb/Shyp/go-circle" {
+		t.Errorf("expected bb/Shyp/go-circle, got %s", slug)
+	}
+}
+
+func TestMatchesRevision(t *testing.T) {
+	p := &Pipeline{VCS: PipelineVCS{Revision: "4e8f3c1a9d"}}
+	for _, rev := range []string{"4e8f3c1", "4e8f3c1a9d", "4e8f3c1a9d5f"} {
+		if !p.MatchesRevision(rev) {
+			t.Errorf("expected %s to match", rev)
+		}
+	}
+	if p.MatchesRevision("4e8f3c2") || p.MatchesRevision("") {
+		t.Error("expected a different revision not to match")
+	}
+}
+
+func TestListPipelines(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.PageSize = 2
+	for _, branch := range []string{"master", "feature", "master", "master"} {
+		addPipeline(s, branch, []string{"success"})
+	}
+	c := newTestClient(s)
+	ctx := context.Background()
+	pp, err := c.ListPipelines(ctx, "github", "Shyp", "go-circle", &PipelineOptions{Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pp.Items) != 2 || pp.Items[0].Number != 4 || pp.Items[1].Number != 3 {
+		t.Fatalf("expected pipelines 4 and 3, got %v", pp.Items)
+	}
+	if pp.NextPageToken == "" {
+		t.Fatal("expected a next page token")
+	}
+	pl := pp.Items[0]
+	if pl.ProjectSlug != "gh/Shyp/go-circle" || pl.VCS.Branch != "master" || pl.VCS.Revision != "4e8f3c1" || !pl.CreatedAt.Valid {
+		t.Errorf("bad pipeline: %#v", pl)
+	}
+	pp, err = c.ListPipelines(ctx, "github", "Shyp", "go-circle", &PipelineOptions{Branch: "master", PageToken: pp.NextPageToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pp.Items) != 1 || pp.Items[0].Number != 1 || pp.NextPageToken != "" {
+		t.Errorf("expected only pipeline 1 on the last page, got %v (next %q)", pp.Items, pp.NextPageToken)
+	}
+
+	got, err := c.GetPipeline(ctx, "Shyp", pl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != pl.ID || got.Number != 4 {
+		t.Errorf("expected pipeline 4, got %#v", got)
+	}
+}
+
+func TestWorkflowsAndJobs(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.PageSize = 2
+	pl := addPipeline(s, "master", []string{"success", "failed", "running"}, &circletest.Workflow{Name: "nightly", Status: "success"})
+	c := newTestClient(s)
+	ctx := context.Background()
+
+	workflows, err := c.ListWorkflows(ctx, "Shyp", pl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workflows) != 2 {
+		t.Fatalf("expected 2 workflows, got %d", len(workflows))
+	}
+	wf := workflows[0]
+	if wf.Name != "build" || wf.Status != WorkflowFailing || wf.PipelineID != pl.ID {
+		t.Errorf("expected a failing build workflow, got %#v", wf)
+	}
+	if wf.Status.IsTerminal() || !wf.Status.IsFailure() {
+		t.Errorf("expected failing to be a non-terminal failure")
+	}
+	got, err := c.GetWorkflow(ctx, "Shyp", wf.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "build" {
+		t.Errorf("expected the build workflow, got %#v", got)
+	}
+
+	// Three jobs with a page size of two checks that ListJobs follows the
+	// page token.
+	jobs, err := c.ListJobs(ctx, "Shyp", wf.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 3 {
+		t.Fatalf("expected 3 jobs, got %d", len(jobs))
+	}
+	test := jobs[1]
+	if test.Name != "test" || test.Status != JobFailed || test.Type != JobTypeBuild || test.JobNumber != 2 {
+		t.Errorf("expected failed test job 2, got %#v", test)
+	}
+	if len(test.Dependencies) != 1 || test.Dependencies[0] != jobs[0].ID {
+		t.Errorf("expected test to depend on build, got %v", test.Dependencies)
+	}
+
+	jd, err := c.GetJob(ctx, "github", "Shyp", "go-circle", test.JobNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jd.Name != "test" || jd.Status != JobFailed || jd.LatestWorkflow.ID != wf.ID || jd.Pipeline.ID != pl.ID {
+		t.Errorf("bad job details: %#v", jd)
+	}
+	if jd.Project.Slug != "gh/Shyp/go-circle" || len(jd.ParallelRuns) != 1 {
+		t.Errorf("bad job details: %#v", jd)
+	}
+}
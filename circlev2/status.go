@@ -0,0 +1,95 @@
+package circlev2
+
+// WorkflowStatus is the status of a workflow. Statuses CircleCI adds in the
+// future are treated as terminal, like unknown statuses in the circle
+// package.
+type WorkflowStatus string
+
+const (
+	WorkflowSuccess      WorkflowStatus = "success"
+	WorkflowRunning      WorkflowStatus = "running"
+	WorkflowNotRun       WorkflowStatus = "not_run"
+	WorkflowFailed       WorkflowStatus = "failed"
+	WorkflowError        WorkflowStatus = "error"
+	WorkflowFailing      WorkflowStatus = "failing"
+	WorkflowOnHold       WorkflowStatus = "on_hold"
+	WorkflowCanceled     WorkflowStatus = "canceled"
+	WorkflowUnauthorized WorkflowStatus = "unauthorized"
+)
+
+// IsTerminal reports whether the workflow has stopped. A workflow that is on
+// hold is waiting for someone to approve a job, so it isn't terminal.
+func (s WorkflowStatus) IsTerminal() bool {
+	switch s {
+	case WorkflowRunning, WorkflowFailing, WorkflowOnHold:
+		return false
+	}
+	return true
+}
+
+// IsSuccess reports whether the workflow passed.
+func (s WorkflowStatus) IsSuccess() bool {
+	return s == WorkflowSuccess
+}
+
+// IsFailure reports whether the workflow failed, or is still running but has
+// a job that failed.
+func (s WorkflowStatus) IsFailure() bool {
+	switch s {
+	case WorkflowFailed, WorkflowError, WorkflowFailing, WorkflowUnauthorized:
+		return true
+	}
+	return false
+}
+
+// JobStatus is the status of a job in a workflow.
+type JobStatus string
+
+const (
+	JobSuccess            JobStatus = "success"
+	JobRunning            JobStatus = "running"
+	JobNotRun             JobStatus = "not_run"
+	JobFailed             JobStatus = "failed"
+	JobRetried            JobStatus = "retried"
+	JobQueued             JobStatus = "queued"
+	JobNotRunning         JobStatus = "not_running"
+	JobInfrastructureFail JobStatus = "infrastructure_fail"
+	JobTimedout           JobStatus = "timedout"
+	JobOnHold             JobStatus = "on_hold"
+	JobTerminatedUnknown  JobStatus = "terminated-unknown"
+	JobBlocked            JobStatus = "blocked"
+	JobCanceled           JobStatus = "canceled"
+	JobUnauthorized       JobStatus = "unauthorized"
+)
+
+// IsPending reports whether the job is waiting to start, including jobs that
+// are blocked on other jobs or waiting for approval.
+func (s JobStatus) IsPending() bool {
+	switch s {
+	case JobQueued, JobNotRunning, JobBlocked, JobOnHold:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether the job has stopped, or will never run. Every
+// status except the pending ones and "running" is terminal, including
+// statuses this package doesn't know about.
+func (s JobStatus) IsTerminal() bool {
+	return !s.IsPending() && s != JobRunning
+}
+
+// IsSuccess reports whether the job passed.
+func (s JobStatus) IsSuccess() bool {
+	return s == JobSuccess
+}
+
+// IsFailure reports whether the job failed, including failures caused by
+// CircleCI itself.
+func (s JobStatus) IsFailure() bool {
+	switch s {
+	case JobFailed, JobInfrastructureFail, JobTimedout, JobTerminatedUnknown, JobUnauthorized:
+		return true
+	}
+	return false
+}
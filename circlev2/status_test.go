@@ -0,0 +1,47 @@
+package circlev2
+
+import "testing"
+
+func TestJobStatus(t *testing.T) {
+	tests := []struct {
+		status                       JobStatus
+		pending, terminal, isFailure bool
+	}{
+		{JobQueued, true, false, false},
+		{JobBlocked, true, false, false},
+		{JobOnHold, true, false, false},
+		{JobRunning, false, false, false},
+		{JobSuccess, false, true, false},
+		{JobFailed, false, true, true},
+		{JobTerminatedUnknown, false, true, true},
+		{JobCanceled, false, true, false},
+		{JobStatus("some_new_status"), false, true, false},
+	}
+	for _, tt := range tests {
+		if got := tt.status.IsPending(); got != tt.pending {
+			t.Errorf("%s: IsPending() = %t, want %t", tt.status, got, tt.pending)
+		}
+		if got := tt.status.IsTerminal(); got != tt.terminal {
+			t.Errorf("%s: IsTerminal() = %t, want %t", tt.status, got, tt.terminal)
+		}
+		if got := tt.status.IsFailure(); got != tt.isFailure {
+			t.Errorf("%s: IsFailure() = %t, want %t", tt.status, got, tt.isFailure)
+		}
+	}
+}
+
+func TestWorkflowStatus(t *testing.T) {
+	for _, s := range []WorkflowStatus{WorkflowRunning, WorkflowFailing, WorkflowOnHold} {
+		if s.IsTerminal() {
+			t.Errorf("%s: expected IsTerminal() to be false", s)
+		}
+	}
+	for _, s := range []WorkflowStatus{WorkflowSuccess, WorkflowFailed, WorkflowCanceled, WorkflowStatus("some_new_status")} {
+		if !s.IsTerminal() {
+			t.Errorf("%s: expected IsTerminal() to be true", s)
+		}
+	}
+	if !WorkflowError.IsFailure() || WorkflowCanceled.IsFailure() {
+		t.Errorf("expected error to be a failure and canceled not to be")
+	}
+}
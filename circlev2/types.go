@@ -0,0 +1,173 @@
+package circlev2
+
+import (
+	"time"
+
+	"github.com/Shyp/go-types"
+)
+
+// Pipeline is a run of a project's configuration, usually started by a push.
+type Pipeline struct {
+	ID          string `json:"id"`
+	ProjectSlug string `json:"project_slug"`
+	Number      int    `json:"number"`
+	// State is "created", "errored", "setup-pending", "setup" or
+	// "pending". It describes the pipeline's configuration, not the
+	// outcome of its workflows.
+	State     string          `json:"state"`
+	CreatedAt types.NullTime  `json:"created_at"`
+	UpdatedAt types.NullTime  `json:"updated_at"`
+	Trigger   Trigger         `json:"trigger"`
+	VCS       PipelineVCS     `json:"vcs"`
+	Errors    []PipelineError `json:"errors"`
+}
+
+// Trigger describes what started a pipeline.
+type Trigger struct {
+	// Type is "webhook", "explicit", "api" or "schedule".
+	Type       string         `json:"type"`
+	ReceivedAt types.NullTime `json:"received_at"`
+	Actor      Actor          `json:"actor"`
+}
+
+// Actor is the user who started a pipeline.
+type Actor struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// MatchesRevision reports whether the pipeline is running revision. Either
+// hash may be abbreviated.
+func (p *Pipeline) MatchesRevision(revision string) bool {
+	n := len(p.VCS.Revision)
+	if len(revision) < n {
+		n = len(revision)
+	}
+	return n > 0 && p.VCS.Revision[:n] == revision[:n]
+}
+
+// PipelineVCS is the commit a pipeline is running.
+type PipelineVCS struct {
+	ProviderName        string `json:"provider_name"`
+	TargetRepositoryURL string `json:"target_repository_url"`
+	OriginRepositoryURL string `json:"origin_repository_url"`
+	Branch              string `json:"branch"`
+	Tag                 string `json:"tag"`
+	Revision            string `json:"revision"`
+	ReviewID            string `json:"review_id"`
+	ReviewURL           string `json:"review_url"`
+	Commit              struct {
+		Subject string `json:"subject"`
+		Body    string `json:"body"`
+	} `json:"commit"`
+}
+
+// PipelineError is a problem CircleCI found with a pipeline, for example an
+// invalid configuration file.
+type PipelineError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Workflow is a graph of jobs run by a pipeline.
+type Workflow struct {
+	ID             string         `json:"id"`
+	Name           string         `json:"name"`
+	PipelineID     string         `json:"pipeline_id"`
+	PipelineNumber int            `json:"pipeline_number"`
+	ProjectSlug    string         `json:"project_slug"`
+	Status         WorkflowStatus `json:"status"`
+	Tag            string         `json:"tag"`
+	StartedBy      string         `json:"started_by"`
+	CanceledBy     string         `json:"canceled_by"`
+	ErroredBy      string         `json:"errored_by"`
+	CreatedAt      types.NullTime `json:"created_at"`
+	StoppedAt      types.NullTime `json:"stopped_at"`
+}
+
+// Job is a job in a workflow.
+type Job struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Type is "build" or "approval".
+	Type JobType `json:"type"`
+	// JobNumber is the v1 build number of the job. It's zero for jobs that
+	// haven't started, and for approval jobs.
+	JobNumber   int       `json:"job_number"`
+	ProjectSlug string    `json:"project_slug"`
+	Status      JobStatus `json:"status"`
+	// Dependencies are the IDs of the jobs that must finish before this
+	// one starts.
+	Dependencies []string       `json:"dependencies"`
+	StartedAt    types.NullTime `json:"started_at"`
+	StoppedAt    types.NullTime `json:"stopped_at"`
+	// ApprovalRequestID identifies an approval job when approving it.
+	ApprovalRequestID string `json:"approval_request_id"`
+	ApprovedBy        string `json:"approved_by"`
+	CanceledBy        string `json:"canceled_by"`
+}
+
+// JobType is the kind of a job in a workflow.
+type JobType string
+
+const (
+	JobTypeBuild    JobType = "build"
+	JobTypeApproval JobType = "approval"
+)
+
+// JobDetails is the full description of a job, as returned by GetJob.
+type JobDetails struct {
+	Number       int           `json:"number"`
+	Name         string        `json:"name"`
+	Status       JobStatus     `json:"status"`
+	WebURL       string        `json:"web_url"`
+	Parallelism  int           `json:"parallelism"`
+	ParallelRuns []ParallelRun `json:"parallel_runs"`
+	Project      JobProject    `json:"project"`
+	Pipeline     struct {
+		ID string `json:"id"`
+	} `json:"pipeline"`
+	LatestWorkflow struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"latest_workflow"`
+	Executor struct {
+		Type          string `json:"type"`
+		ResourceClass string `json:"resource_class"`
+	} `json:"executor"`
+	Contexts []struct {
+		Name string `json:"name"`
+	} `json:"contexts"`
+	Organization struct {
+		Name string `json:"name"`
+	} `json:"organization"`
+	Messages []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+		Reason  string `json:"reason"`
+	} `json:"messages"`
+	// Duration is the number of milliseconds the job ran for.
+	Duration  int64          `json:"duration"`
+	CreatedAt types.NullTime `json:"created_at"`
+	QueuedAt  types.NullTime `json:"queued_at"`
+	StartedAt types.NullTime `json:"started_at"`
+	StoppedAt types.NullTime `json:"stopped_at"`
+}
+
+// RunDuration returns how long the job ran for.
+func (jd *JobDetails) RunDuration() time.Duration {
+	return time.Duration(jd.Duration) * time.Millisecond
+}
+
+// ParallelRun is the status of one container of a job.
+type ParallelRun struct {
+	Index  int       `json:"index"`
+	Status JobStatus `json:"status"`
+}
+
+// JobProject is the project a job belongs to.
+type JobProject struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	ExternalURL string `json:"external_url"`
+}
@@ -0,0 +1,307 @@
+package circle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// DefaultBaseURL is the root of the CircleCI API. Versioned paths like
+// "/v1.1/project" are appended to it.
+const DefaultBaseURL = "https://circleci.com/api"
+
+var defaultUserAgent = fmt.Sprintf("circle-command-line-client/%s", VERSION)
+
+// Client makes requests against the CircleCI API. The zero value is usable,
+// but NewClient fills in sensible defaults for every field.
+type Client struct {
+	// BaseURL is the scheme, host and path prefix for API requests, for
+	// example "https://circleci.com/api". Defaults to DefaultBaseURL.
+	BaseURL string
+
+	// Tokens looks up the API token for an organization. Defaults to
+	// a ConfigTokenSource.
+	Tokens TokenSource
+
+	// HTTPClient is used to make requests. Defaults to an http.Client that
+	// uses DefaultTransport and has a ten second timeout.
+	HTTPClient *http.Client
+
+	// UserAgent is sent in the User-Agent header of every request.
+	UserAgent string
+
+	// Retry controls how failed requests are retried. Defaults to
+	// DefaultRetryPolicy; set it to NoRetries to disable retries.
+	Retry *RetryPolicy
+
+	// Concurrency is the maximum number of requests the client makes at
+	// once in fan-out operations like FailureTexts and DownloadArtifacts.
+	// The limit is shared between all of them. Defaults to
+	// DefaultConcurrency.
+	Concurrency int
+
+	// Logger, if set, records the method, path, status, latency and retry
+	// count of every request. API tokens are removed from every record.
+	Logger log.Logger
+
+	// LogBodies adds the start of request and response bodies to the records
+	// written to Logger.
+	LogBodies bool
+
+	// Cache, if set, stores GET responses on disk and serves them to later
+	// requests for the same path; see DiskCache.
+	Cache *DiskCache
+
+	semOnce sync.Once
+	sem     chan struct{}
+
+	// secrets holds every token the client has sent, so they can be
+	// removed from errors and debug output.
+	secrets redactor
+}
+
+// NewClient returns a Client that reads tokens from the configuration file and
+// talks to circleci.com.
+//
+// If the CIRCLE_DEBUG environment variable is "true", the client logs every
+// request and response body to stderr.
+func NewClient() *Client {
+	c := &Client{
+		BaseURL:    DefaultBaseURL,
+		Tokens:     new(ConfigTokenSource),
+		HTTPClient: newHTTPClient(),
+		UserAgent:  defaultUserAgent,
+	}
+	debugFromEnv(c)
+	return c
+}
+
+// DefaultClient is used by the package level functions like GetTree and
+// GetBuild.
+var DefaultClient = NewClient()
+
+// newHTTPClient returns the HTTP client used by clients that don't set one.
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: DefaultTransport,
+		Timeout:   10 * time.Second,
+	}
+}
+
+var defaultHTTPClient = newHTTPClient()
+
+var defaultTokenSource = new(ConfigTokenSource)
+
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimSuffix(c.BaseURL, "/")
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return defaultHTTPClient
+	}
+	return c.HTTPClient
+}
+
+func (c *Client) retryPolicy() *RetryPolicy {
+	if c.Retry == nil {
+		return DefaultRetryPolicy
+	}
+	return c.Retry
+}
+
+func (c *Client) token(org string) (string, error) {
+	var token string
+	var err error
+	if c.Tokens == nil {
+		token, err = defaultTokenSource.Token(org)
+	} else {
+		token, err = c.Tokens.Token(org)
+	}
+	if err != nil {
+		return "", err
+	}
+	c.secrets.add(token)
+	return token, nil
+}
+
+// orgKey is the context key for the organization a request was made on behalf
+// of, so errors can say which token was rejected.
+type orgKey struct{}
+
+func requestOrg(req *http.Request) string {
+	org, _ := req.Context().Value(orgKey{}).(string)
+	return org
+}
+
+// newRequest builds a request for the given path, sending the token for org
+// in the Circle-Token header. If path is an absolute URL it is used as is,
+// otherwise it's appended to the client's BaseURL.
+func (c *Client) newRequest(ctx context.Context, org, method, path string, body io.Reader) (*http.Request, error) {
+	token, err := c.token(org)
+	if err != nil {
+		return nil, err
+	}
+	var uri string
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		uri = path
+	} else {
+		uri = c.baseURL() + path
+	}
+	req, err := http.NewRequest(method, uri, body)
+	if err != nil {
+		return nil, c.secrets.redactError(err)
+	}
+	req = req.WithContext(context.WithValue(ctx, orgKey{}, org))
+	req.Header.Set(tokenHeader, token)
+	req.Header.Set("Accept", "application/json")
+	if c.UserAgent == "" {
+		req.Header.Set("User-Agent", defaultUserAgent)
+	} else {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	return req, nil
+}
+
+// send makes the request, retrying according to the client's RetryPolicy, and
+// returns the response if it has a 2xx or 3xx status code, or an *Error
+// otherwise. The caller is responsible for closing the response body.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, attempts, err := c.sendRetry(req)
+	err = c.secrets.redactError(err)
+	if c.Logger != nil {
+		resp = c.logExchange(req, resp, err, attempts, time.Since(start))
+	}
+	return resp, err
+}
+
+// sendRetry returns the response, and the number of attempts it took to get
+// it.
+func (c *Client) sendRetry(req *http.Request) (*http.Response, int, error) {
+	policy := c.retryPolicy()
+	hc := *c.httpClient()
+	hc.CheckRedirect = stripTokenOnRedirect(hc.CheckRedirect)
+	for attempt := 1; ; attempt++ {
+		resp, err := hc.Do(req)
+		wait, retry := policy.retry(req, resp, err, attempt)
+		if !retry {
+			if err != nil {
+				return nil, attempt, err
+			}
+			if resp.StatusCode >= 400 {
+				defer resp.Body.Close()
+				return nil, attempt, parseError(resp, requestOrg(req))
+			}
+			return resp, attempt, nil
+		}
+		if c.Logger != nil {
+			ctx := []interface{}{"method", req.Method, "path", c.secrets.redact(redactedPath(req.URL)), "attempt", attempt, "wait", wait}
+			if err != nil {
+				ctx = append(ctx, "err", c.secrets.redactError(err).Error())
+			} else {
+				ctx = append(ctx, "status", resp.StatusCode)
+			}
+			c.Logger.Debug(RetryLogMessage, ctx...)
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, attempt, err
+		}
+		req, err = rewind(req)
+		if err != nil {
+			return nil, attempt, err
+		}
+	}
+}
+
+// do makes the request and decodes the JSON response body into v, if v is not
+// nil.
+func (c *Client) do(req *http.Request, v interface{}) error {
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return c.secrets.redactError(err)
+		}
+	}
+	// Read to EOF, including any whitespace after the JSON value, so the
+	// connection can be reused.
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return c.secrets.redactError(err)
+}
+
+// Do makes an API request for path and decodes the JSON response body into v,
+// if v is not nil. path is appended to BaseURL, for example
+// "/v2/pipeline/<id>", and the token for org is sent with the request. If
+// body is not nil, it is encoded as JSON and sent as the request body.
+//
+// Do uses the client's retries, logging and cache, like every other method.
+// It's meant for packages like circlev2 that call endpoints this package
+// doesn't have methods for.
+func (c *Client) Do(ctx context.Context, org, method, path string, body, v interface{}) error {
+	if method == "GET" && body == nil && v != nil {
+		return c.get(ctx, org, path, v)
+	}
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := c.newRequest(ctx, org, method, path, r)
+	if err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
+
+func (c *Client) get(ctx context.Context, org, path string, v interface{}) error {
+	if c.Cache != nil {
+		return c.cachedGet(ctx, org, path, v)
+	}
+	req, err := c.newRequest(ctx, org, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
+
+func (c *Client) post(ctx context.Context, org, path string, body io.Reader, v interface{}) error {
+	req, err := c.newRequest(ctx, org, "POST", path, body)
+	if err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
+
+func (c *Client) delete(ctx context.Context, org, path string, v interface{}) error {
+	req, err := c.newRequest(ctx, org, "DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
@@ -0,0 +1,89 @@
+package circle
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientGetBuild(t *testing.T) {
+	var gotPath, gotToken, gotUA string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotToken = r.Header.Get("Circle-Token")
+		gotUA = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"build_num": 15, "reponame": "go-circle", "username": "Shyp", "parallel": 2}`))
+	}))
+	defer s.Close()
+	c := &Client{
+		BaseURL:   s.URL,
+		Tokens:    StaticToken("secret"),
+		UserAgent: "circle-test",
+	}
+	cb, err := c.GetBuild(context.Background(), "Shyp", "go-circle", 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.BuildNum != 15 || cb.Parallel != 2 {
+		t.Errorf("bad build: %#v", cb)
+	}
+	if gotPath != "/v1/project/Shyp/go-circle/15" {
+		t.Errorf("expected path to be /v1/project/Shyp/go-circle/15, got %s", gotPath)
+	}
+	if gotToken != "secret" {
+		t.Errorf("expected token to be secret, got %q", gotToken)
+	}
+	if gotUA != "circle-test" {
+		t.Errorf("expected User-Agent to be circle-test, got %q", gotUA)
+	}
+}
+
+func TestClientErrorStatus(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte(`{"message": "Build not found"}`))
+	}))
+	defer s.Close()
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("secret")}
+	_, err := c.GetTree(context.Background(), "Shyp", "go-circle", "master")
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
+
+func TestClientDo(t *testing.T) {
+	var gotMethod, gotPath, gotBody string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		body, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Write([]byte(`{"message": "Accepted."}`))
+	}))
+	defer s.Close()
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("secret"), Retry: NoRetries}
+	var resp struct {
+		Message string `json:"message"`
+	}
+	body := map[string]string{"name": "hold"}
+	if err := c.Do(context.Background(), "Shyp", "POST", "/v2/workflow/abc/approve/def", body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if gotMethod != "POST" || gotPath != "/v2/workflow/abc/approve/def" {
+		t.Errorf("expected POST /v2/workflow/abc/approve/def, got %s %s", gotMethod, gotPath)
+	}
+	if gotBody != `{"name":"hold"}` {
+		t.Errorf("expected a JSON body, got %q", gotBody)
+	}
+	if resp.Message != "Accepted." {
+		t.Errorf("expected the response to be decoded, got %q", resp.Message)
+	}
+	if err := c.Do(context.Background(), "Shyp", "GET", "/v2/me", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if gotMethod != "GET" || gotBody != "" {
+		t.Errorf("expected a GET without a body, got %s with %q", gotMethod, gotBody)
+	}
+}
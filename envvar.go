@@ -0,0 +1,82 @@
+package circle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// EnvVar is an environment variable set on a project. CircleCI never returns
+// the values of environment variables; Value holds a masked copy, like
+// "xxxx1234", that ends with the last few characters of the real value.
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func getEnvVarUri(vcsType, org, project, name string) string {
+	uri := getProjectUri(vcsType, org, project) + "/envvar"
+	if name != "" {
+		uri += "/" + url.PathEscape(name)
+	}
+	return uri
+}
+
+// ListEnvVars returns the environment variables set on the project, with
+// their values masked. vcsType is "github" or "bitbucket".
+func (c *Client) ListEnvVars(ctx context.Context, vcsType, org, project string) ([]*EnvVar, error) {
+	var vars []*EnvVar
+	if err := c.get(ctx, org, getEnvVarUri(vcsType, org, project, ""), &vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// GetEnvVar returns the environment variable with the given name, with its
+// value masked. If the variable is not set, errors.Is(err, ErrNotFound)
+// reports true.
+func (c *Client) GetEnvVar(ctx context.Context, vcsType, org, project, name string) (*EnvVar, error) {
+	ev := new(EnvVar)
+	if err := c.get(ctx, org, getEnvVarUri(vcsType, org, project, name), ev); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// SetEnvVar sets an environment variable on the project, replacing any
+// existing value, and returns it with the value masked. The request body is
+// never logged, even if the client's LogBodies is true.
+func (c *Client) SetEnvVar(ctx context.Context, vcsType, org, project, name, value string) (*EnvVar, error) {
+	data, err := json.Marshal(&EnvVar{Name: name, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	ev := new(EnvVar)
+	if err := c.post(withSecretBody(ctx), org, getEnvVarUri(vcsType, org, project, ""), bytes.NewReader(data), ev); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// DeleteEnvVar removes an environment variable from the project. CircleCI
+// doesn't return an error if the variable is not set.
+func (c *Client) DeleteEnvVar(ctx context.Context, vcsType, org, project, name string) error {
+	return c.delete(ctx, org, getEnvVarUri(vcsType, org, project, name), nil)
+}
+
+func ListEnvVars(ctx context.Context, vcsType, org, project string) ([]*EnvVar, error) {
+	return DefaultClient.ListEnvVars(ctx, vcsType, org, project)
+}
+
+func GetEnvVar(ctx context.Context, vcsType, org, project, name string) (*EnvVar, error) {
+	return DefaultClient.GetEnvVar(ctx, vcsType, org, project, name)
+}
+
+func SetEnvVar(ctx context.Context, vcsType, org, project, name, value string) (*EnvVar, error) {
+	return DefaultClient.SetEnvVar(ctx, vcsType, org, project, name, value)
+}
+
+func DeleteEnvVar(ctx context.Context, vcsType, org, project, name string) error {
+	return DefaultClient.DeleteEnvVar(ctx, vcsType, org, project, name)
+}
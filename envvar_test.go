@@ -0,0 +1,80 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Shyp/go-circle/circletest"
+	log "github.com/inconshreveable/log15"
+)
+
+func TestEnvVars(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddProject("github", "Shyp", "go-circle")
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: NoRetries}
+	ctx := context.Background()
+
+	ev, err := c.SetEnvVar(ctx, "github", "Shyp", "go-circle", "AWS_SECRET", "abcdef123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Name != "AWS_SECRET" || ev.Value != "xxxx3456" {
+		t.Errorf("expected a masked AWS_SECRET, got %#v", ev)
+	}
+	if value, _ := s.EnvVar("Shyp", "go-circle", "AWS_SECRET"); value != "abcdef123456" {
+		t.Errorf("expected the server to store the real value, got %q", value)
+	}
+	if _, err := c.SetEnvVar(ctx, "github", "Shyp", "go-circle", "DEBUG", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	vars, err := c.ListEnvVars(ctx, "github", "Shyp", "go-circle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 2 || vars[0].Name != "AWS_SECRET" || vars[1].Name != "DEBUG" {
+		t.Errorf("expected AWS_SECRET and DEBUG, got %v", vars)
+	}
+	ev, err = c.GetEnvVar(ctx, "github", "Shyp", "go-circle", "DEBUG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Name != "DEBUG" || ev.Value != "xxxx" {
+		t.Errorf("expected a masked DEBUG, got %#v", ev)
+	}
+
+	if err := c.DeleteEnvVar(ctx, "github", "Shyp", "go-circle", "DEBUG"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetEnvVar(ctx, "github", "Shyp", "go-circle", "DEBUG")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a 404 after deleting DEBUG, got %v", err)
+	}
+}
+
+func TestSetEnvVarNotLogged(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddProject("github", "Shyp", "go-circle")
+	var records []*log.Record
+	c := &Client{
+		BaseURL:   s.URL,
+		Tokens:    StaticToken("token"),
+		Retry:     NoRetries,
+		Logger:    recordLogger(&records),
+		LogBodies: true,
+	}
+	if _, err := c.SetEnvVar(context.Background(), "github", "Shyp", "go-circle", "AWS_SECRET", "abcdef123456"); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	body, _ := ctxValue(records[0], "request_body").(string)
+	if strings.Contains(body, "abcdef123456") || body != redacted {
+		t.Errorf("expected the request body to be redacted, got %q", body)
+	}
+}
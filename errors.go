@@ -0,0 +1,152 @@
+package circle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for common API failures. Use errors.Is to check whether an
+// error returned by a Client matches one of these, for example:
+//
+//	if errors.Is(err, circle.ErrNotFound) { ... }
+var (
+	ErrNotFound     = errors.New("circle: not found")
+	ErrUnauthorized = errors.New("circle: unauthorized")
+	ErrRateLimited  = errors.New("circle: rate limited")
+	ErrServer       = errors.New("circle: server error")
+)
+
+// Error is returned when the CircleCI API responds with a 4xx or 5xx status
+// code.
+type Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Message is the "message" field of the response body, for example
+	// "Build not found". If the body isn't JSON, Message holds the start of
+	// the body instead.
+	Message string
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the path and query string of the request, with the API token
+	// redacted.
+	Path string
+	// Org is the organization whose token was used for the request.
+	Org string
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("circle: %s %s: %s (status %d)", e.Method, e.Path, msg, e.StatusCode)
+}
+
+// Is reports whether e matches one of the sentinel errors in this package.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// RateLimitError is returned when the API responds with a 429.
+type RateLimitError struct {
+	Err *Error
+	// RetryAfter is the amount of time the server asked us to wait before
+	// trying again, or zero if it didn't say.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s, retry after %s", e.Err.Error(), e.RetryAfter)
+	}
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+const redacted = "REDACTED"
+
+// redactedPath returns the path and query of u with the circle-token
+// parameter redacted.
+func redactedPath(u *url.URL) string {
+	query := u.Query()
+	if _, ok := query["circle-token"]; ok {
+		query.Set("circle-token", redacted)
+	}
+	if len(query) == 0 {
+		return u.Path
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(h string, now time.Time) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(h)); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+const maxErrorBody = 512
+
+// parseError reads the response body and returns an *Error, or
+// a *RateLimitError for a 429.
+func parseError(resp *http.Response, org string) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	e := Error{
+		StatusCode: resp.StatusCode,
+		Method:     resp.Request.Method,
+		Path:       redactedPath(resp.Request.URL),
+		Org:        org,
+	}
+	var msg struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &msg); err == nil && msg.Message != "" {
+		e.Message = msg.Message
+	} else {
+		text := strings.TrimSpace(string(body))
+		if len(text) > maxErrorBody {
+			text = text[:maxErrorBody] + "…"
+		}
+		e.Message = text
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{
+			Err:        &e,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return &e
+}
@@ -0,0 +1,90 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorTypes(t *testing.T) {
+	tests := []struct {
+		code     int
+		body     string
+		sentinel error
+		message  string
+	}{
+		{404, `{"message": "Build not found"}`, ErrNotFound, "Build not found"},
+		{401, `{"message": "You must log in first."}`, ErrUnauthorized, "You must log in first."},
+		{403, `{"message": "Permission denied"}`, ErrUnauthorized, "Permission denied"},
+		{502, `<html>Bad Gateway</html>`, ErrServer, "<html>Bad Gateway</html>"},
+		{429, `{"message": "Slow down"}`, ErrRateLimited, "Slow down"},
+	}
+	for _, tt := range tests {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(tt.code)
+			w.Write([]byte(tt.body))
+		}))
+		c := &Client{BaseURL: s.URL, Tokens: StaticToken("secret-token"), Retry: NoRetries}
+		_, err := c.GetBuild(context.Background(), "Shyp", "go-circle", 3)
+		s.Close()
+		if !errors.Is(err, tt.sentinel) {
+			t.Errorf("%d: expected errors.Is(err, %v) to be true, err was %v", tt.code, tt.sentinel, err)
+			continue
+		}
+		var cerr *Error
+		if !errors.As(err, &cerr) {
+			t.Errorf("%d: expected err to be a *Error, was %#v", tt.code, err)
+			continue
+		}
+		if cerr.StatusCode != tt.code {
+			t.Errorf("expected status %d, got %d", tt.code, cerr.StatusCode)
+		}
+		if cerr.Message != tt.message {
+			t.Errorf("expected message %q, got %q", tt.message, cerr.Message)
+		}
+		if cerr.Org != "Shyp" {
+			t.Errorf("expected org Shyp, got %q", cerr.Org)
+		}
+		if strings.Contains(err.Error(), "secret-token") {
+			t.Errorf("error message contains the token: %v", err)
+		}
+		if !strings.HasPrefix(cerr.Path, "/v1/project/Shyp/go-circle/3") {
+			t.Errorf("bad path: %q", cerr.Path)
+		}
+		if tt.code == 429 {
+			var rerr *RateLimitError
+			if !errors.As(err, &rerr) {
+				t.Fatalf("expected a *RateLimitError, got %#v", err)
+			}
+			if rerr.RetryAfter != 7*time.Second {
+				t.Errorf("expected RetryAfter to be 7s, got %v", rerr.RetryAfter)
+			}
+		} else if errors.Is(err, ErrRateLimited) {
+			t.Errorf("%d: should not match ErrRateLimited", tt.code)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-3", 0},
+		{"Sun, 01 Jan 2017 00:01:00 GMT", time.Minute},
+		{"garbage", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.in, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q): got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package circle
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shyp/go-circle/circletest"
+)
+
+var record = flag.Bool("record", false, "Record the fixtures in testdata/fixtures against the real CircleCI API")
+
+const fixtureDir = "testdata/fixtures"
+
+// fixtureClient returns a Client that replays the responses in
+// testdata/fixtures. Run "go test -record" with a token in your config file
+// to refresh them.
+func fixtureClient() *Client {
+	if *record {
+		c := NewClient()
+		c.HTTPClient = &http.Client{
+			Transport: circletest.NewRecorder(fixtureDir, nil),
+			Timeout:   30 * time.Second,
+		}
+		return c
+	}
+	return &Client{
+		Tokens:     StaticToken("token"),
+		HTTPClient: circletest.NewReplayClient(fixtureDir),
+		Retry:      NoRetries,
+	}
+}
+
+func TestTreeStatusFixtures(t *testing.T) {
+	c := fixtureClient()
+	cr, err := c.GetTree(context.Background(), "Shyp", "go-circle", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type state struct{ passed, failed, notRunning, running bool }
+	want := map[string]state{
+		"success":             {passed: true},
+		"fixed":               {passed: true},
+		"failed":              {failed: true},
+		"timedout":            {failed: true},
+		"no_tests":            {failed: true},
+		"infrastructure_fail": {failed: true},
+		"not_running":         {notRunning: true},
+		"scheduled":           {notRunning: true},
+		"queued":              {notRunning: true},
+		"running":             {running: true},
+	}
+	seen := make(map[string]bool)
+	for _, tb := range *cr {
+		w, ok := want[string(tb.Status)]
+		if !ok {
+			t.Errorf("unexpected status %q in fixture", tb.Status)
+			continue
+		}
+		seen[string(tb.Status)] = true
+		got := state{tb.Passed(), tb.Failed(), tb.NotRunning(), tb.Running()}
+		if got != w {
+			t.Errorf("%s: got %+v, want %+v", tb.Status, got, w)
+		}
+	}
+	for status := range want {
+		if !seen[status] {
+			t.Errorf("no build with status %q in the fixtures", status)
+		}
+	}
+}
+
+func TestFailuresFixture(t *testing.T) {
+	c := fixtureClient()
+	ctx := context.Background()
+	cb, err := c.GetBuild(ctx, "Shyp", "go-circle", 1283)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]int{{4, 0}, {5, 0}}
+	if got := cb.Failures(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Failures(): got %v, want %v", got, want)
+	}
+	texts, err := c.FailureTexts(ctx, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 2 {
+		t.Fatalf("expected 2 failure texts, got %d", len(texts))
+	}
+	if !strings.Contains(texts[0], "--- FAIL: TestWaitFailed") || !strings.Contains(texts[0], "make: *** [test] Error 1") {
+		t.Errorf("bad first failure text: %q", texts[0])
+	}
+	if !strings.Contains(texts[1], "should omit type string") {
+		t.Errorf("bad second failure text: %q", texts[1])
+	}
+
+	passed, err := c.GetBuild(ctx, "Shyp", "go-circle", 1281)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures := passed.Failures(); len(failures) != 0 {
+		t.Errorf("expected no failures for a passing build, got %v", failures)
+	}
+}
+
+func TestStatisticsFixture(t *testing.T) {
+	c := fixtureClient()
+	cb, err := c.GetBuild(context.Background(), "Shyp", "go-circle", 1283)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := cb.Statistics()
+	lines := strings.Split(strings.TrimSpace(stats), "\n")
+	// header, separator, one line per step
+	if len(lines) != 2+len(cb.Steps) {
+		t.Fatalf("expected %d lines, got %d:\n%s", 2+len(cb.Steps), len(lines), stats)
+	}
+	if !strings.HasPrefix(lines[0], "Step") || !strings.Contains(lines[0], "0       1") {
+		t.Errorf("bad header line: %q", lines[0])
+	}
+	if !strings.Contains(stats, "1m41s") || !strings.Contains(stats, "1m38s") {
+		t.Errorf("expected rounded make test durations in output:\n%s", stats)
+	}
+	if !strings.Contains(stats, "go vet ./... && megacheck ./... && go list …") {
+		t.Errorf("expected long step name to be truncated:\n%s", stats)
+	}
+}
+
+func TestBuildModelFixture(t *testing.T) {
+	c := fixtureClient()
+	ctx := context.Background()
+	cb, err := c.GetBuild(ctx, "Shyp", "go-circle", 1283)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.Branch != "master" || cb.Subject != "Add Statistics output" || cb.Why != "github" {
+		t.Errorf("bad branch, subject or why: %q %q %q", cb.Branch, cb.Subject, cb.Why)
+	}
+	if cb.Lifecycle != "finished" || cb.Outcome != "failed" {
+		t.Errorf("bad lifecycle or outcome: %q %q", cb.Lifecycle, cb.Outcome)
+	}
+	if cb.CommitterName != "Kevin Burke" || cb.CommitterEmail != circletest.ScrubbedEmail || cb.AuthorName != "Kevin Burke" {
+		t.Errorf("bad committer or author: %q %q %q", cb.CommitterName, cb.CommitterEmail, cb.AuthorName)
+	}
+	if cb.User == nil || cb.User.Login != "kevinburke" {
+		t.Errorf("bad user: %#v", cb.User)
+	}
+	if len(cb.AllCommitDetails) != 1 || cb.AllCommitDetails[0].Subject != cb.Subject {
+		t.Errorf("bad commit details: %#v", cb.AllCommitDetails)
+	}
+	if got := cb.QueueDuration(); got != 14*time.Second {
+		t.Errorf("QueueDuration: got %v, want 14s", got)
+	}
+	if got := cb.RunDuration(); got != 3*time.Minute+3*time.Second {
+		t.Errorf("RunDuration: got %v, want 3m3s", got)
+	}
+	if got := cb.TotalDuration(); got != 3*time.Minute+17*time.Second {
+		t.Errorf("TotalDuration: got %v, want 3m17s", got)
+	}
+	a := cb.Steps[4].Actions[0]
+	if a.Step != 4 || a.Index != 0 || a.BashCommand != "make test" || !a.HasOutput || a.Type != "test" {
+		t.Errorf("bad action: %#v", a)
+	}
+	if a.ExitCode == nil || *a.ExitCode != 1 {
+		t.Errorf("expected exit code 1, got %v", a.ExitCode)
+	}
+	if got := a.EndTime.Time.Sub(a.StartTime.Time); got != 101234*time.Millisecond {
+		t.Errorf("bad action start and end times: %v", got)
+	}
+	if a.Duration() != 101234*time.Millisecond {
+		t.Errorf("bad action duration: %v", a.Duration())
+	}
+
+	cr, err := c.GetTree(ctx, "Shyp", "go-circle", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb := (*cr)[0]
+	if len(tb.PullRequests) != 1 || tb.PullRequests[0].URL != "https://github.com/Shyp/go-circle/pull/40" {
+		t.Errorf("bad pull requests: %#v", tb.PullRequests)
+	}
+	if tb.Lifecycle != "running" || tb.Outcome != "" || tb.StopTime.Valid {
+		t.Errorf("bad running build: %q %q %v", tb.Lifecycle, tb.Outcome, tb.StopTime)
+	}
+	if tb.QueueDuration() != 14*time.Second {
+		t.Errorf("QueueDuration: got %v, want 14s", tb.QueueDuration())
+	}
+	if tb.RunDuration() < time.Hour {
+		t.Errorf("expected a build that's still running to count up to now, got %v", tb.RunDuration())
+	}
+}
@@ -0,0 +1,207 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// BuildFilter restricts a list of builds to those in a group of statuses.
+type BuildFilter string
+
+const (
+	FilterCompleted  BuildFilter = "completed"
+	FilterSuccessful BuildFilter = "successful"
+	FilterFailed     BuildFilter = "failed"
+	FilterRunning    BuildFilter = "running"
+)
+
+// MaxPageSize is the largest number of builds CircleCI returns in one
+// response.
+const MaxPageSize = 100
+
+// TreeOptions selects a page of builds. The zero value gets the first page,
+// with the API's default size, of builds in every status.
+type TreeOptions struct {
+	// Limit is the number of builds to return, at most MaxPageSize. If zero,
+	// CircleCI returns 30 builds.
+	Limit int
+	// Offset is the number of builds to skip, counting from the most
+	// recent.
+	Offset int
+	// Filter, if set, only returns builds in the given group of statuses.
+	Filter BuildFilter
+}
+
+func (o *TreeOptions) query() string {
+	if o == nil {
+		return ""
+	}
+	v := url.Values{}
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		v.Set("offset", strconv.Itoa(o.Offset))
+	}
+	if o.Filter != "" {
+		v.Set("filter", string(o.Filter))
+	}
+	if len(v) == 0 {
+		return ""
+	}
+	return "?" + v.Encode()
+}
+
+func getProjectUri(vcsType string, org string, project string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", v11Prefix, vcsType, org, project)
+}
+
+// GetTreePage returns a page of builds for the given branch, most recent
+// first. If opts is nil, the first page is returned.
+func (c *Client) GetTreePage(ctx context.Context, org, project, branch string, opts *TreeOptions) (*CircleTreeResponse, error) {
+	cr := new(CircleTreeResponse)
+	if err := c.get(ctx, org, getTreeUri(org, project, branch)+opts.query(), cr); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// GetProjectBuilds returns a page of builds for every branch of the project,
+// most recent first. vcsType is "github" or "bitbucket".
+func (c *Client) GetProjectBuilds(ctx context.Context, vcsType, org, project string, opts *TreeOptions) (*CircleTreeResponse, error) {
+	cr := new(CircleTreeResponse)
+	if err := c.get(ctx, org, getProjectUri(vcsType, org, project)+opts.query(), cr); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// GetRecentBuilds returns a page of the most recent builds for every project
+// the user that owns org's token follows. CircleCI can't filter this list, so
+// opts.Filter is ignored.
+func (c *Client) GetRecentBuilds(ctx context.Context, org string, opts *TreeOptions) (*CircleTreeResponse, error) {
+	if opts != nil {
+		o := *opts
+		o.Filter = ""
+		opts = &o
+	}
+	cr := new(CircleTreeResponse)
+	if err := c.get(ctx, org, "/v1.1/recent-builds"+opts.query(), cr); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// ErrNoMoreBuilds is returned by BuildIterator.Next when there are no more
+// builds to return.
+var ErrNoMoreBuilds = errors.New("circle: no more builds")
+
+// BuildIterator walks a list of builds, most recent first, fetching a page at
+// a time as it goes. It is not safe for concurrent use.
+type BuildIterator struct {
+	// Since, if set, stops the iteration at the first build that was
+	// queued before it.
+	Since time.Time
+
+	fetch func(ctx context.Context, opts *TreeOptions) (*CircleTreeResponse, error)
+	opts  TreeOptions
+	page  []TreeBuild
+	last  bool
+	err   error
+}
+
+// TreeIterator returns an iterator over the builds for a branch. opts.Limit
+// sets the page size, which defaults to MaxPageSize, and opts.Offset the
+// number of builds to skip. opts may be nil.
+func (c *Client) TreeIterator(org, project, branch string, opts *TreeOptions) *BuildIterator {
+	return newBuildIterator(opts, func(ctx context.Context, opts *TreeOptions) (*CircleTreeResponse, error) {
+		return c.GetTreePage(ctx, org, project, branch, opts)
+	})
+}
+
+// ProjectIterator returns an iterator over the builds for every branch of a
+// project. opts is used as in TreeIterator.
+func (c *Client) ProjectIterator(vcsType, org, project string, opts *TreeOptions) *BuildIterator {
+	return newBuildIterator(opts, func(ctx context.Context, opts *TreeOptions) (*CircleTreeResponse, error) {
+		return c.GetProjectBuilds(ctx, vcsType, org, project, opts)
+	})
+}
+
+// RecentIterator returns an iterator over the recent builds for every project
+// the user that owns org's token follows. opts is used as in TreeIterator,
+// except that opts.Filter is ignored.
+func (c *Client) RecentIterator(org string, opts *TreeOptions) *BuildIterator {
+	return newBuildIterator(opts, func(ctx context.Context, opts *TreeOptions) (*CircleTreeResponse, error) {
+		return c.GetRecentBuilds(ctx, org, opts)
+	})
+}
+
+func newBuildIterator(opts *TreeOptions, fetch func(context.Context, *TreeOptions) (*CircleTreeResponse, error)) *BuildIterator {
+	it := &BuildIterator{fetch: fetch}
+	if opts != nil {
+		it.opts = *opts
+	}
+	if it.opts.Limit <= 0 || it.opts.Limit > MaxPageSize {
+		it.opts.Limit = MaxPageSize
+	}
+	return it
+}
+
+// Next returns the next build. It returns ErrNoMoreBuilds when every build has
+// been returned or the Since cutoff is reached, and ctx.Err() if ctx is
+// canceled. Once Next returns an error, it returns the same error on every
+// later call.
+func (it *BuildIterator) Next(ctx context.Context) (*TreeBuild, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return nil, err
+	}
+	if len(it.page) == 0 {
+		if it.last {
+			it.err = ErrNoMoreBuilds
+			return nil, it.err
+		}
+		opts := it.opts
+		cr, err := it.fetch(ctx, &opts)
+		if err != nil {
+			it.err = err
+			return nil, err
+		}
+		it.page = *cr
+		it.opts.Offset += len(it.page)
+		// A short page means there's nothing after it, so we don't need to
+		// ask for an empty one.
+		it.last = len(it.page) < it.opts.Limit
+		if len(it.page) == 0 {
+			it.err = ErrNoMoreBuilds
+			return nil, it.err
+		}
+	}
+	tb := it.page[0]
+	it.page = it.page[1:]
+	if !it.Since.IsZero() && tb.QueuedAt.Valid && tb.QueuedAt.Time.Before(it.Since) {
+		it.page = nil
+		it.err = ErrNoMoreBuilds
+		return nil, it.err
+	}
+	return &tb, nil
+}
+
+func GetTreePage(ctx context.Context, org, project, branch string, opts *TreeOptions) (*CircleTreeResponse, error) {
+	return DefaultClient.GetTreePage(ctx, org, project, branch, opts)
+}
+
+func GetProjectBuilds(ctx context.Context, vcsType, org, project string, opts *TreeOptions) (*CircleTreeResponse, error) {
+	return DefaultClient.GetProjectBuilds(ctx, vcsType, org, project, opts)
+}
+
+func GetRecentBuilds(ctx context.Context, org string, opts *TreeOptions) (*CircleTreeResponse, error) {
+	return DefaultClient.GetRecentBuilds(ctx, org, opts)
+}
@@ -0,0 +1,158 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func newIteratorServer(t *testing.T) (*circletest.Server, *Client) {
+	t.Helper()
+	s := circletest.NewServer()
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []string{"success", "failed", "running", "success", "fixed", "timedout", "success"}
+	for i, status := range statuses {
+		branch := "master"
+		if i%2 == 1 {
+			branch = "feature"
+		}
+		s.AddBuild("Shyp", "go-circle", &circletest.Build{
+			Branch:   branch,
+			Status:   status,
+			QueuedAt: start.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	return s, &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: NoRetries}
+}
+
+// drain returns the build numbers returned by it before ErrNoMoreBuilds.
+func drain(t *testing.T, it *BuildIterator) []int {
+	t.Helper()
+	var nums []int
+	for {
+		tb, err := it.Next(context.Background())
+		if err == ErrNoMoreBuilds {
+			return nums
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		nums = append(nums, tb.BuildNum)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetTreePage(t *testing.T) {
+	s, c := newIteratorServer(t)
+	defer s.Close()
+	cr, err := c.GetTreePage(context.Background(), "Shyp", "go-circle", "master", &TreeOptions{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*cr) != 2 || (*cr)[0].BuildNum != 5 || (*cr)[1].BuildNum != 3 {
+		t.Errorf("expected builds 5 and 3, got %v", *cr)
+	}
+	cr, err = c.GetTreePage(context.Background(), "Shyp", "go-circle", "master", &TreeOptions{Filter: FilterSuccessful})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tb := range *cr {
+		if !tb.Passed() {
+			t.Errorf("filter: expected only successful builds, got %d with status %s", tb.BuildNum, tb.Status)
+		}
+	}
+	if len(*cr) != 3 {
+		t.Errorf("filter: expected 3 builds, got %d", len(*cr))
+	}
+}
+
+func TestTreeIterator(t *testing.T) {
+	s, c := newIteratorServer(t)
+	defer s.Close()
+	nums := drain(t, c.TreeIterator("Shyp", "go-circle", "master", &TreeOptions{Limit: 2}))
+	if want := []int{7, 5, 3, 1}; !equalInts(nums, want) {
+		t.Errorf("expected builds %v, got %v", want, nums)
+	}
+}
+
+func TestProjectIterator(t *testing.T) {
+	s, c := newIteratorServer(t)
+	defer s.Close()
+	nums := drain(t, c.ProjectIterator("github", "Shyp", "go-circle", &TreeOptions{Limit: 3}))
+	if want := []int{7, 6, 5, 4, 3, 2, 1}; !equalInts(nums, want) {
+		t.Errorf("expected builds %v, got %v", want, nums)
+	}
+	nums = drain(t, c.ProjectIterator("github", "Shyp", "go-circle", &TreeOptions{Limit: 2, Filter: FilterFailed}))
+	if want := []int{6, 2}; !equalInts(nums, want) {
+		t.Errorf("filter: expected builds %v, got %v", want, nums)
+	}
+}
+
+func TestIteratorSince(t *testing.T) {
+	s, c := newIteratorServer(t)
+	defer s.Close()
+	it := c.ProjectIterator("github", "Shyp", "go-circle", &TreeOptions{Limit: 2})
+	// Builds are queued an hour apart starting at midnight, so this includes
+	// builds 4 through 7.
+	it.Since = time.Date(2018, 1, 1, 2, 30, 0, 0, time.UTC)
+	nums := drain(t, it)
+	if want := []int{7, 6, 5, 4}; !equalInts(nums, want) {
+		t.Errorf("expected builds %v, got %v", want, nums)
+	}
+}
+
+func TestIteratorCanceled(t *testing.T) {
+	s, c := newIteratorServer(t)
+	defer s.Close()
+	it := c.TreeIterator("Shyp", "go-circle", "master", &TreeOptions{Limit: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := it.Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := it.Next(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, err := it.Next(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the error to stick, got %v", err)
+	}
+}
+
+func TestRecentBuilds(t *testing.T) {
+	s, c := newIteratorServer(t)
+	defer s.Close()
+	s.AddBuild("Shyp", "api", &circletest.Build{
+		Branch:   "deploy",
+		Status:   "running",
+		QueuedAt: time.Date(2018, 1, 1, 3, 30, 0, 0, time.UTC),
+	})
+	cr, err := c.GetRecentBuilds(context.Background(), "Shyp", &TreeOptions{Limit: 4, Filter: FilterFailed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*cr) != 4 {
+		t.Fatalf("expected 4 builds, got %d", len(*cr))
+	}
+	first := (*cr)[3]
+	if first.Project() != "Shyp/api" || first.Branch != "deploy" || first.BuildNum != 1 {
+		t.Errorf("expected Shyp/api build 1 on deploy, got %s build %d on %s", first.Project(), first.BuildNum, first.Branch)
+	}
+	nums := drain(t, c.RecentIterator("Shyp", &TreeOptions{Limit: 3}))
+	if want := []int{7, 6, 5, 1, 4, 3, 2, 1}; !equalInts(nums, want) {
+		t.Errorf("expected builds %v, got %v", want, nums)
+	}
+}
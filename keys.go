@@ -0,0 +1,112 @@
+package circle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/Shyp/go-types"
+)
+
+// CheckoutKeyType is the kind of key CircleCI uses to check out a project.
+type CheckoutKeyType string
+
+const (
+	// DeployKey is a read-only key for a single repository.
+	DeployKey CheckoutKeyType = "deploy-key"
+	// UserKey is a key added to the GitHub account of the user who owns the
+	// API token, which can check out any repository they can read.
+	UserKey CheckoutKeyType = "github-user-key"
+)
+
+// CheckoutKey is a key CircleCI uses to check out a project.
+type CheckoutKey struct {
+	PublicKey   string          `json:"public_key"`
+	Type        CheckoutKeyType `json:"type"`
+	Fingerprint string          `json:"fingerprint"`
+	// Preferred is true for the key CircleCI uses when a project has more
+	// than one.
+	Preferred bool           `json:"preferred"`
+	Time      types.NullTime `json:"time"`
+}
+
+func getCheckoutKeyUri(vcsType, org, project, fingerprint string) string {
+	uri := getProjectUri(vcsType, org, project) + "/checkout-key"
+	if fingerprint != "" {
+		uri += "/" + url.PathEscape(fingerprint)
+	}
+	return uri
+}
+
+// ListCheckoutKeys returns the checkout keys for the project. vcsType is
+// "github" or "bitbucket".
+func (c *Client) ListCheckoutKeys(ctx context.Context, vcsType, org, project string) ([]*CheckoutKey, error) {
+	var keys []*CheckoutKey
+	if err := c.get(ctx, org, getCheckoutKeyUri(vcsType, org, project, ""), &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CreateCheckoutKey creates a new checkout key for the project, and installs
+// the public half on the code host.
+func (c *Client) CreateCheckoutKey(ctx context.Context, vcsType, org, project string, keyType CheckoutKeyType) (*CheckoutKey, error) {
+	data, err := json.Marshal(map[string]CheckoutKeyType{"type": keyType})
+	if err != nil {
+		return nil, err
+	}
+	key := new(CheckoutKey)
+	if err := c.post(ctx, org, getCheckoutKeyUri(vcsType, org, project, ""), bytes.NewReader(data), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetCheckoutKey returns the checkout key with the given fingerprint, which
+// looks like "c9:0b:1c:...".
+func (c *Client) GetCheckoutKey(ctx context.Context, vcsType, org, project, fingerprint string) (*CheckoutKey, error) {
+	key := new(CheckoutKey)
+	if err := c.get(ctx, org, getCheckoutKeyUri(vcsType, org, project, fingerprint), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeleteCheckoutKey deletes the checkout key with the given fingerprint.
+func (c *Client) DeleteCheckoutKey(ctx context.Context, vcsType, org, project, fingerprint string) error {
+	return c.delete(ctx, org, getCheckoutKeyUri(vcsType, org, project, fingerprint), nil)
+}
+
+// AddSSHKey uploads a private key that builds of the project can use to
+// connect to hostname, for example to deploy. If hostname is empty, the key
+// is used for every host. privateKey is a PEM-encoded key, like the one
+// returned by GenerateSSHKey. The request body is never logged.
+func (c *Client) AddSSHKey(ctx context.Context, vcsType, org, project, hostname, privateKey string) error {
+	data, err := json.Marshal(map[string]string{"hostname": hostname, "private_key": privateKey})
+	if err != nil {
+		return err
+	}
+	uri := getProjectUri(vcsType, org, project) + "/ssh-key"
+	return c.post(withSecretBody(ctx), org, uri, bytes.NewReader(data), nil)
+}
+
+func ListCheckoutKeys(ctx context.Context, vcsType, org, project string) ([]*CheckoutKey, error) {
+	return DefaultClient.ListCheckoutKeys(ctx, vcsType, org, project)
+}
+
+func CreateCheckoutKey(ctx context.Context, vcsType, org, project string, keyType CheckoutKeyType) (*CheckoutKey, error) {
+	return DefaultClient.CreateCheckoutKey(ctx, vcsType, org, project, keyType)
+}
+
+func GetCheckoutKey(ctx context.Context, vcsType, org, project, fingerprint string) (*CheckoutKey, error) {
+	return DefaultClient.GetCheckoutKey(ctx, vcsType, org, project, fingerprint)
+}
+
+func DeleteCheckoutKey(ctx context.Context, vcsType, org, project, fingerprint string) error {
+	return DefaultClient.DeleteCheckoutKey(ctx, vcsType, org, project, fingerprint)
+}
+
+func AddSSHKey(ctx context.Context, vcsType, org, project, hostname, privateKey string) error {
+	return DefaultClient.AddSSHKey(ctx, vcsType, org, project, hostname, privateKey)
+}
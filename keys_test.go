@@ -0,0 +1,74 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func TestCheckoutKeys(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddProject("github", "Shyp", "go-circle")
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: NoRetries}
+	ctx := context.Background()
+
+	deploy, err := c.CreateCheckoutKey(ctx, "github", "Shyp", "go-circle", DeployKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deploy.Type != DeployKey || !deploy.Preferred || deploy.Fingerprint == "" {
+		t.Errorf("expected a preferred deploy key, got %#v", deploy)
+	}
+	user, err := c.CreateCheckoutKey(ctx, "github", "Shyp", "go-circle", UserKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := c.ListCheckoutKeys(ctx, "github", "Shyp", "go-circle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[1].Fingerprint != user.Fingerprint || keys[1].Preferred {
+		t.Errorf("expected the user key to be listed second, got %v", keys)
+	}
+	key, err := c.GetCheckoutKey(ctx, "github", "Shyp", "go-circle", user.Fingerprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.PublicKey != user.PublicKey {
+		t.Errorf("expected public key %q, got %q", user.PublicKey, key.PublicKey)
+	}
+
+	if err := c.DeleteCheckoutKey(ctx, "github", "Shyp", "go-circle", deploy.Fingerprint); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetCheckoutKey(ctx, "github", "Shyp", "go-circle", deploy.Fingerprint)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a 404 after deleting the deploy key, got %v", err)
+	}
+}
+
+func TestAddSSHKey(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddProject("github", "Shyp", "go-circle")
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: NoRetries}
+	key, err := GenerateSSHKey("deploy@go-circle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddSSHKey(context.Background(), "github", "Shyp", "go-circle", "deploy.example.com", key.PrivateKey); err != nil {
+		t.Fatal(err)
+	}
+	keys := s.SSHKeys("Shyp", "go-circle")
+	if len(keys) != 1 || keys[0].Hostname != "deploy.example.com" || keys[0].PrivateKey != key.PrivateKey {
+		t.Errorf("expected the key to be uploaded for deploy.example.com, got %v", keys)
+	}
+	err = c.AddSSHKey(context.Background(), "github", "Shyp", "go-circle", "", "not a key")
+	var cerr *Error
+	if !errors.As(err, &cerr) || cerr.StatusCode != 400 {
+		t.Errorf("expected a 400 for an invalid key, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package circle
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultConcurrency is the number of requests a Client makes at once in
+// fan-out operations like FailureTexts and DownloadArtifacts, if its
+// Concurrency field is not set.
+const DefaultConcurrency = 8
+
+// limiter returns the semaphore shared by all of the client's fan-out
+// operations.
+func (c *Client) limiter() chan struct{} {
+	c.semOnce.Do(func() {
+		n := c.Concurrency
+		if n <= 0 {
+			n = DefaultConcurrency
+		}
+		c.sem = make(chan struct{}, n)
+	})
+	return c.sem
+}
+
+// forEach calls fn once for each i in [0, n), with at most c.Concurrency calls
+// in flight across every forEach running on the client. Once a call returns an
+// error, no new calls are started and the context passed to in-flight calls is
+// canceled; forEach returns the first error.
+func (c *Client) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	sem := c.limiter()
+	group, errctx := errgroup.WithContext(ctx)
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-errctx.Done():
+			// Either fn failed, or ctx was canceled; don't start any more.
+			break loop
+		}
+		if errctx.Err() != nil {
+			<-sem
+			break
+		}
+		i := i
+		group.Go(func() error {
+			defer func() { <-sem }()
+			return fn(errctx, i)
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
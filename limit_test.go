@@ -0,0 +1,74 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachLimit(t *testing.T) {
+	c := &Client{Concurrency: 3}
+	var inFlight, max int32
+	results := make([]int, 20)
+	err := c.forEach(context.Background(), len(results), func(ctx context.Context, i int) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		results[i] = i * 2
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max > 3 {
+		t.Errorf("expected at most 3 calls in flight, got %d", max)
+	}
+	for i := range results {
+		if results[i] != i*2 {
+			t.Errorf("results[%d]: got %d, want %d", i, results[i], i*2)
+		}
+	}
+}
+
+func TestForEachStopsOnError(t *testing.T) {
+	c := &Client{Concurrency: 1}
+	var calls int32
+	errBoom := errors.New("boom")
+	err := c.forEach(context.Background(), 10, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 2 {
+			return errBoom
+		}
+		return nil
+	})
+	if err != errBoom {
+		t.Fatalf("expected boom error, got %v", err)
+	}
+	if calls > 4 {
+		t.Errorf("expected forEach to stop after the error, made %d calls", calls)
+	}
+	// The semaphore should be fully released.
+	if len(c.limiter()) != 0 {
+		t.Errorf("expected no slots in use, got %d", len(c.limiter()))
+	}
+}
+
+func TestForEachCanceled(t *testing.T) {
+	c := new(Client)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := c.forEach(ctx, 5, func(ctx context.Context, i int) error {
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
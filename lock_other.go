@@ -0,0 +1,23 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package circle
+
+import (
+	"errors"
+	"os"
+)
+
+// errCacheUnsupported is returned on platforms where the cache can't lock its
+// files. Concurrent processes could overwrite each other's entries there, so
+// the cache is disabled: NewDiskCache fails, and a DiskCache built by hand
+// misses on every request.
+var errCacheUnsupported error = errors.New("circle: the disk cache is not supported on this platform")
+
+func lockFile(f *os.File, exclusive bool) error {
+	return errCacheUnsupported
+}
+
+func unlockFile(f *os.File) error {
+	return errCacheUnsupported
+}
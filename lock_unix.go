@@ -0,0 +1,26 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package circle
+
+import (
+	"os"
+	"syscall"
+)
+
+// errCacheUnsupported is nil, because lockFile works on this platform.
+var errCacheUnsupported error
+
+// lockFile takes an advisory lock on f, shared if exclusive is false. It
+// blocks until the lock is available.
+func lockFile(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	return syscall.Flock(int(f.Fd()), how)
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
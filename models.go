@@ -0,0 +1,151 @@
+package circle
+
+import (
+	"time"
+
+	"github.com/Shyp/go-types"
+)
+
+// User is the CircleCI user who started a build.
+type User struct {
+	IsUser    bool   `json:"is_user"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+	VCSType   string `json:"vcs_type"`
+	ID        int64  `json:"id"`
+}
+
+// CommitDetails describes one of the commits included in a build.
+type CommitDetails struct {
+	Commit         string         `json:"commit"`
+	CommitURL      string         `json:"commit_url"`
+	Subject        string         `json:"subject"`
+	Body           string         `json:"body"`
+	Branch         string         `json:"branch"`
+	AuthorName     string         `json:"author_name"`
+	AuthorEmail    string         `json:"author_email"`
+	AuthorLogin    string         `json:"author_login"`
+	AuthorDate     types.NullTime `json:"author_date"`
+	CommitterName  string         `json:"committer_name"`
+	CommitterEmail string         `json:"committer_email"`
+	CommitterLogin string         `json:"committer_login"`
+	CommitterDate  types.NullTime `json:"committer_date"`
+}
+
+// PullRequest is an open pull request for the commit being built.
+type PullRequest struct {
+	HeadSHA string `json:"head_sha"`
+	URL     string `json:"url"`
+}
+
+// SSHUser is a user who has been granted SSH access to a build.
+type SSHUser struct {
+	Login    string `json:"login"`
+	GithubID int64  `json:"github_id"`
+}
+
+// Node is a container a build ran on.
+type Node struct {
+	ImageID      string `json:"image_id"`
+	Port         int    `json:"port"`
+	PublicIPAddr string `json:"public_ip_addr"`
+	Username     string `json:"username"`
+	SSHEnabled   bool   `json:"ssh_enabled"`
+}
+
+// Picard describes the machines a build ran on, for builds on CircleCI 2.0.
+type Picard struct {
+	BuildAgent    *BuildAgent    `json:"build_agent"`
+	Executor      string         `json:"executor"`
+	ResourceClass *ResourceClass `json:"resource_class"`
+}
+
+// BuildAgent is the version of the agent that ran a build.
+type BuildAgent struct {
+	Image      string            `json:"image"`
+	Properties map[string]string `json:"properties"`
+}
+
+// ResourceClass is the size of the containers a build ran on.
+type ResourceClass struct {
+	CPU   float64 `json:"cpu"`
+	RAM   int     `json:"ram"`
+	Class string  `json:"class"`
+}
+
+// Message is a note CircleCI attached to a build or an action, for example a
+// warning about the configuration.
+type Message struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+}
+
+// queuedAt returns the time a build was queued. Older builds only have
+// usage_queued_at set.
+func queuedAt(queued, usageQueued types.NullTime) types.NullTime {
+	if queued.Valid {
+		return queued
+	}
+	return usageQueued
+}
+
+// between returns the time from start to end, or from start to now if end
+// is not set. It returns zero if start is not set.
+func between(start, end types.NullTime, now time.Time) time.Duration {
+	if !start.Valid {
+		return 0
+	}
+	if end.Valid {
+		return end.Time.Sub(start.Time)
+	}
+	return now.Sub(start.Time)
+}
+
+// QueueDuration returns how long the build waited before it started running.
+// If the build hasn't started yet, it's the time it has been waiting so far.
+func (tb TreeBuild) QueueDuration() time.Duration {
+	return between(queuedAt(tb.QueuedAt, tb.UsageQueuedAt), tb.StartTime, time.Now())
+}
+
+// RunDuration returns how long the build ran for, or how long it has been
+// running so far if it hasn't finished. It's zero if the build hasn't
+// started.
+func (tb TreeBuild) RunDuration() time.Duration {
+	return between(tb.StartTime, tb.StopTime, time.Now())
+}
+
+// TotalDuration returns the time from the build being queued to finishing, or
+// to now if it hasn't finished.
+func (tb TreeBuild) TotalDuration() time.Duration {
+	return between(queuedAt(tb.QueuedAt, tb.UsageQueuedAt), tb.StopTime, time.Now())
+}
+
+// QueueDuration returns how long the build waited before it started running.
+// If the build hasn't started yet, it's the time it has been waiting so far.
+func (cb *CircleBuild) QueueDuration() time.Duration {
+	return between(queuedAt(cb.QueuedAt, cb.UsageQueuedAt), cb.StartTime, time.Now())
+}
+
+// RunDuration returns how long the build ran for, or how long it has been
+// running so far if it hasn't finished. It's zero if the build hasn't
+// started.
+func (cb *CircleBuild) RunDuration() time.Duration {
+	return between(cb.StartTime, cb.StopTime, time.Now())
+}
+
+// TotalDuration returns the time from the build being queued to finishing, or
+// to now if it hasn't finished.
+func (cb *CircleBuild) TotalDuration() time.Duration {
+	return between(queuedAt(cb.QueuedAt, cb.UsageQueuedAt), cb.StopTime, time.Now())
+}
+
+// Duration returns how long the action ran for, or how long it has been
+// running so far if it hasn't finished.
+func (a *Action) Duration() time.Duration {
+	if a.Runtime > 0 {
+		return time.Duration(a.Runtime)
+	}
+	return between(a.StartTime, a.EndTime, time.Now())
+}
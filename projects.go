@@ -0,0 +1,162 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Shyp/go-types"
+)
+
+// Project is a project followed by the owner of an API token, as returned by
+// ListProjects.
+type Project struct {
+	VCSURL        string `json:"vcs_url"`
+	VCSType       string `json:"vcs_type"`
+	Username      string `json:"username"`
+	RepoName      string `json:"reponame"`
+	Following     bool   `json:"following"`
+	DefaultBranch string `json:"default_branch"`
+	// Branches summarizes the recent builds on each branch CircleCI knows
+	// about, keyed by branch name.
+	Branches map[string]BranchSummary `json:"branches"`
+}
+
+// Name returns the organization and name of the project, for example
+// "Shyp/go-circle".
+func (p *Project) Name() string {
+	return p.Username + "/" + p.RepoName
+}
+
+// BranchSummary describes the recent builds on a branch.
+type BranchSummary struct {
+	PusherLogins   []string      `json:"pusher_logins"`
+	LastSuccess    *BranchBuild  `json:"last_success"`
+	LastNonSuccess *BranchBuild  `json:"last_non_success"`
+	RecentBuilds   []BranchBuild `json:"recent_builds"`
+	RunningBuilds  []BranchBuild `json:"running_builds"`
+}
+
+// LastBuild returns the most recent build on the branch, or nil if there
+// aren't any.
+func (bs BranchSummary) LastBuild() *BranchBuild {
+	if len(bs.RunningBuilds) > 0 {
+		return &bs.RunningBuilds[0]
+	}
+	if len(bs.RecentBuilds) > 0 {
+		return &bs.RecentBuilds[0]
+	}
+	last := bs.LastSuccess
+	if last == nil || (bs.LastNonSuccess != nil && bs.LastNonSuccess.BuildNum > last.BuildNum) {
+		last = bs.LastNonSuccess
+	}
+	return last
+}
+
+// BranchBuild is the short description of a build in a BranchSummary.
+type BranchBuild struct {
+	BuildNum    int            `json:"build_num"`
+	Status      Status         `json:"status"`
+	Outcome     Outcome        `json:"outcome"`
+	VCSRevision string         `json:"vcs_revision"`
+	PushedAt    types.NullTime `json:"pushed_at"`
+	AddedAt     types.NullTime `json:"added_at"`
+}
+
+// FollowResponse is the response to following or unfollowing a project.
+type FollowResponse struct {
+	Following bool `json:"following"`
+	// FirstBuild is the build CircleCI started when the project was
+	// followed for the first time. It's nil if CircleCI didn't start a
+	// build.
+	FirstBuild *CircleBuild `json:"first_build"`
+}
+
+// VCSType returns the CircleCI name, "github" or "bitbucket", for the code
+// host in a remote URL.
+func VCSType(host string) (string, error) {
+	switch {
+	case strings.Contains(host, "github.com"):
+		return "github", nil
+	case strings.Contains(host, "bitbucket.org"):
+		return "bitbucket", nil
+	default:
+		return "", fmt.Errorf("unknown code host %s", host)
+	}
+}
+
+// ListProjects returns every project followed by the owner of org's token.
+func (c *Client) ListProjects(ctx context.Context, org string) ([]*Project, error) {
+	var projects []*Project
+	if err := c.get(ctx, org, "/v1.1/projects", &projects); err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		// CircleCI escapes the branch names used as keys, so
+		// "feature/foo" comes back as "feature%2Ffoo".
+		branches := make(map[string]BranchSummary, len(p.Branches))
+		for name, summary := range p.Branches {
+			if unescaped, err := url.PathUnescape(name); err == nil {
+				name = unescaped
+			}
+			branches[name] = summary
+		}
+		p.Branches = branches
+	}
+	return projects, nil
+}
+
+// Follow follows the project, which turns on builds for it. vcsType is
+// "github" or "bitbucket".
+func (c *Client) Follow(ctx context.Context, vcsType, org, repoName string) (*FollowResponse, error) {
+	uri := fmt.Sprintf("%s/%s/%s/%s/follow", v11Prefix, vcsType, org, repoName)
+	fr := new(FollowResponse)
+	if err := c.post(ctx, org, uri, nil, fr); err != nil {
+		return nil, err
+	}
+	if !fr.Following {
+		return fr, errors.New("not following the project")
+	}
+	return fr, nil
+}
+
+// Unfollow stops following the project, which turns off builds for it.
+func (c *Client) Unfollow(ctx context.Context, vcsType, org, repoName string) (*FollowResponse, error) {
+	uri := fmt.Sprintf("%s/%s/%s/%s/unfollow", v11Prefix, vcsType, org, repoName)
+	fr := new(FollowResponse)
+	if err := c.post(ctx, org, uri, nil, fr); err != nil {
+		return nil, err
+	}
+	if fr.Following {
+		return fr, errors.New("still following the project")
+	}
+	return fr, nil
+}
+
+// Disable unfollows the project on CircleCI, which turns off builds for it.
+func (c *Client) Disable(ctx context.Context, host string, org string, repoName string) error {
+	vcs, err := VCSType(host)
+	if err != nil {
+		return fmt.Errorf("can't disable: %v", err)
+	}
+	_, err = c.Unfollow(ctx, vcs, org, repoName)
+	return err
+}
+
+func ListProjects(ctx context.Context, org string) ([]*Project, error) {
+	return DefaultClient.ListProjects(ctx, org)
+}
+
+func Follow(ctx context.Context, vcsType, org, repoName string) (*FollowResponse, error) {
+	return DefaultClient.Follow(ctx, vcsType, org, repoName)
+}
+
+func Unfollow(ctx context.Context, vcsType, org, repoName string) (*FollowResponse, error) {
+	return DefaultClient.Unfollow(ctx, vcsType, org, repoName)
+}
+
+func Disable(ctx context.Context, host string, org string, repoName string) error {
+	return DefaultClient.Disable(ctx, host, org, repoName)
+}
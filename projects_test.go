@@ -0,0 +1,79 @@
+package circle
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func TestListProjects(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "success"})
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "feature/paging", Status: "failed"})
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "feature/paging", Status: "running"})
+	s.AddBuild("Shyp", "unfollowed", &circletest.Build{Branch: "master", Status: "success"})
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: NoRetries}
+	ctx := context.Background()
+	fr, err := c.Follow(ctx, "github", "Shyp", "go-circle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr.FirstBuild == nil || fr.FirstBuild.BuildNum != 3 {
+		t.Errorf("expected the first build to be 3, got %#v", fr.FirstBuild)
+	}
+
+	projects, err := c.ListProjects(ctx, "Shyp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 {
+		t.Fatalf("expected 1 followed project, got %d", len(projects))
+	}
+	p := projects[0]
+	if p.Name() != "Shyp/go-circle" || p.VCSType != "github" || p.DefaultBranch != "master" {
+		t.Errorf("bad project: %#v", p)
+	}
+	summary, ok := p.Branches["feature/paging"]
+	if !ok {
+		t.Fatalf("expected an unescaped feature/paging branch, got %v", p.Branches)
+	}
+	if last := summary.LastBuild(); last == nil || last.BuildNum != 3 || last.Status != "running" {
+		t.Errorf("expected the last build to be running build 3, got %#v", last)
+	}
+	if summary.LastNonSuccess == nil || summary.LastNonSuccess.BuildNum != 2 {
+		t.Errorf("expected the last non-success to be build 2, got %#v", summary.LastNonSuccess)
+	}
+	if last := p.Branches["master"].LastBuild(); last == nil || last.BuildNum != 1 {
+		t.Errorf("expected the last master build to be 1, got %#v", last)
+	}
+
+	if err := c.Disable(ctx, "github.com", "Shyp", "go-circle"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Following("Shyp", "go-circle") {
+		t.Error("expected Disable to unfollow the project")
+	}
+}
+
+func TestVCSType(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"github.com", "github"},
+		{"git@github.com", "github"},
+		{"bitbucket.org", "bitbucket"},
+		{"gitlab.com", ""},
+	}
+	for _, tt := range tests {
+		got, err := VCSType(tt.host)
+		if got != tt.want {
+			t.Errorf("VCSType(%q): got %q, want %q", tt.host, got, tt.want)
+		}
+		if (err != nil) != (tt.want == "") {
+			t.Errorf("VCSType(%q): unexpected error %v", tt.host, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// tokenHeader is the header used to send the API token.
+const tokenHeader = "Circle-Token"
+
+// redactor remembers secret values and scrubs them from strings.
+type redactor struct {
+	mu      sync.RWMutex
+	secrets map[string]struct{}
+}
+
+func (r *redactor) add(secret string) {
+	if secret == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.secrets == nil {
+		r.secrets = make(map[string]struct{})
+	}
+	r.secrets[secret] = struct{}{}
+}
+
+// redact replaces every secret in s with "REDACTED".
+func (r *redactor) redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for secret := range r.secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	return s
+}
+
+func (r *redactor) contains(s string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for secret := range r.secrets {
+		if strings.Contains(s, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedError has the same chain as the error it wraps, for errors.Is and
+// errors.As, but its message has had every token removed.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError returns err with any token the client has used removed from its
+// message. *url.Error values are copied with the URL redacted, so callers can
+// keep inspecting them with a type assertion.
+func (r *redactor) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if uerr, ok := err.(*url.Error); ok {
+		u, perr := url.Parse(uerr.URL)
+		if perr == nil {
+			uerr = &url.Error{Op: uerr.Op, URL: uerr.URL, Err: uerr.Err}
+			query := u.Query()
+			if _, ok := query["circle-token"]; ok {
+				query.Set("circle-token", redacted)
+				u.RawQuery = query.Encode()
+			}
+			uerr.URL = r.redact(u.String())
+			err = uerr
+		}
+	}
+	msg := err.Error()
+	if !r.contains(msg) {
+		return err
+	}
+	return &redactedError{msg: r.redact(msg), err: err}
+}
+
+// errTooManyRedirects matches the default policy of net/http.
+var errTooManyRedirects = errors.New("stopped after 10 redirects")
+
+// stripTokenOnRedirect returns a CheckRedirect function that removes the
+// token header when a request is redirected to a different host, for
+// example when an artifact download redirects to S3, and then calls next.
+func stripTokenOnRedirect(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > 0 && req.URL.Host != via[0].URL.Host {
+			req.Header.Del(tokenHeader)
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errTooManyRedirects
+		}
+		return nil
+	}
+}
+
+type secretBodyKey struct{}
+
+// withSecretBody marks requests made with ctx as having a body that must not
+// be logged, for example one that sets an environment variable.
+func withSecretBody(ctx context.Context) context.Context {
+	return context.WithValue(ctx, secretBodyKey{}, true)
+}
+
+func hasSecretBody(req *http.Request) bool {
+	secret, _ := req.Context().Value(secretBodyKey{}).(bool)
+	return secret
+}
@@ -0,0 +1,73 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactError(t *testing.T) {
+	var r redactor
+	r.add("s3cret")
+	err := r.redactError(&url.Error{Op: "Get", URL: "https://circleci.com/api?circle-token=s3cret", Err: errors.New("boom s3cret")})
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("error contains secret: %v", err)
+	}
+	var uerr *url.Error
+	if !errors.As(err, &uerr) {
+		t.Errorf("expected a *url.Error, got %#v", err)
+	}
+	if r.redactError(nil) != nil {
+		t.Error("expected redactError(nil) to be nil")
+	}
+}
+
+func TestNetworkErrorRedacted(t *testing.T) {
+	c := &Client{BaseURL: "http://localhost:11233", Tokens: StaticToken("s3cret-token"), Retry: NoRetries}
+	_, err := c.GetBuild(context.Background(), "Shyp", "go-circle", 1)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if strings.Contains(err.Error(), "s3cret-token") {
+		t.Errorf("error contains the token: %v", err)
+	}
+	if !IsRetryable(err) {
+		t.Errorf("expected redacted error to still be retryable, got %v", err)
+	}
+}
+
+func TestTokenNotSentOnCrossHostRedirect(t *testing.T) {
+	var gotToken string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.Header.Get(tokenHeader)
+		w.Write([]byte("artifact"))
+	}))
+	defer other.Close()
+	// 127.0.0.1 and localhost are different hosts as far as redirects are
+	// concerned.
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(tokenHeader) != "s3cret" {
+			t.Errorf("expected token header on the first request")
+		}
+		http.Redirect(w, r, otherURL+"/file.txt", http.StatusFound)
+	}))
+	defer s.Close()
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("s3cret")}
+	req, err := c.newRequest(context.Background(), "Shyp", "GET", "/artifact", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if gotToken != "" {
+		t.Errorf("token was forwarded to a different host: %q", gotToken)
+	}
+}
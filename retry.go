@@ -0,0 +1,159 @@
+package circle
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy controls how a Client retries requests that fail with
+// a network error, a 429, or a 5xx status code.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times to try a request, including
+	// the first. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// MinBackoff is the base wait before the first retry. The wait doubles
+	// with each attempt, up to MaxBackoff, and is randomized by up to half to
+	// keep clients from retrying in lockstep.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// MaxRetryAfter is the longest Retry-After the client will honor. If the
+	// server asks us to wait longer than this, the request fails with
+	// a RateLimitError instead.
+	MaxRetryAfter time.Duration
+
+	// RetryNonIdempotent allows retrying POST and PATCH requests. By default
+	// only idempotent methods are retried.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy is used by Clients that don't set a RetryPolicy.
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts:   4,
+	MinBackoff:    500 * time.Millisecond,
+	MaxBackoff:    10 * time.Second,
+	MaxRetryAfter: time.Minute,
+}
+
+// NoRetries is a RetryPolicy that makes every request exactly once.
+var NoRetries = &RetryPolicy{MaxAttempts: 1}
+
+// IsRetryable reports whether err is a request timeout or a network failure
+// that is likely to go away if the request is tried again.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// some net.OpError's are wrapped in a url.Error
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	switch err := err.(type) {
+	default:
+		return false
+	case *net.OpError:
+		return err.Op == "dial" && err.Net == "tcp"
+	case *net.DNSError:
+		return true
+	// Catchall, this needs to go last.
+	case net.Error:
+		return err.Timeout() || err.Temporary()
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the time to wait before the next attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retry reports whether a request that has been tried attempt times should
+// be tried again, and how long to wait first. Exactly one of resp and err is
+// non-nil.
+func (p *RetryPolicy) retry(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || req.Context().Err() != nil {
+		return 0, false
+	}
+	if !p.RetryNonIdempotent && !isIdempotent(req.Method) {
+		return 0, false
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// can't rewind the body to send it again
+		return 0, false
+	}
+	if err != nil {
+		return p.backoff(attempt), IsRetryable(err)
+	}
+	if !isRetryableStatus(resp.StatusCode) {
+		return 0, false
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if wait := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); wait > 0 {
+			if p.MaxRetryAfter > 0 && wait > p.MaxRetryAfter {
+				return 0, false
+			}
+			return wait, true
+		}
+	}
+	return p.backoff(attempt), true
+}
+
+// rewind returns a copy of req that can be sent again.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package circle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func makeRequest(client http.Client, method, uri string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("User-Agent", "retry-test")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func TestIsRetryable(t *testing.T) {
+	client := http.Client{
+		Timeout: 200 * time.Millisecond,
+	}
+	_, err := makeRequest(client, "GET", "http://localhost:11233")
+	if !IsRetryable(err) {
+		t.Fatalf("expected err to be retryable, was %s", err)
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	defer s.Close()
+	_, err = makeRequest(client, "GET", s.URL)
+	if !IsRetryable(err) {
+		t.Fatalf("expected err to be retryable, was %s", err)
+	}
+}
+
+var fastRetries = &RetryPolicy{
+	MaxAttempts:   3,
+	MinBackoff:    time.Millisecond,
+	MaxBackoff:    5 * time.Millisecond,
+	MaxRetryAfter: 2 * time.Second,
+}
+
+func TestRetryServerError(t *testing.T) {
+	var count int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.WriteHeader(502)
+			return
+		}
+		w.Write([]byte(`{"build_num": 7}`))
+	}))
+	defer s.Close()
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: fastRetries}
+	cb, err := c.GetBuild(context.Background(), "Shyp", "go-circle", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.BuildNum != 7 {
+		t.Errorf("expected build 7, got %d", cb.BuildNum)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 requests, got %d", count)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var count int32
+	var first time.Time
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(429)
+			return
+		}
+		if since := time.Since(first); since < 900*time.Millisecond {
+			t.Errorf("retried too soon: %v", since)
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer s.Close()
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: fastRetries}
+	if _, err := c.GetTree(context.Background(), "Shyp", "go-circle", "master"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 requests, got %d", count)
+	}
+}
+
+func TestNoRetryNonIdempotent(t *testing.T) {
+	var count int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(503)
+	}))
+	defer s.Close()
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: fastRetries}
+	err := c.Rebuild(context.Background(), &TreeBuild{Username: "Shyp", RepoName: "go-circle", VCSType: "github", BuildNum: 3})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if count != 1 {
+		t.Errorf("expected 1 request, got %d", count)
+	}
+
+	policy := *fastRetries
+	policy.RetryNonIdempotent = true
+	c.Retry = &policy
+	atomic.StoreInt32(&count, 0)
+	err = c.Rebuild(context.Background(), &TreeBuild{Username: "Shyp", RepoName: "go-circle", VCSType: "github", BuildNum: 3})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected a 503 error, got %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 requests, got %d", count)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := &RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt := 1; attempt < 10; attempt++ {
+		d := p.backoff(attempt)
+		if d < 50*time.Millisecond || d > time.Second {
+			t.Errorf("backoff(%d) = %v, out of range", attempt, d)
+		}
+	}
+}
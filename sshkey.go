@@ -0,0 +1,100 @@
+package circle
+
+import (
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// SSHKey is a key pair generated by GenerateSSHKey.
+type SSHKey struct {
+	// PrivateKey is the private key in the PEM-encoded OpenSSH format, as
+	// written by ssh-keygen.
+	PrivateKey string
+	// PublicKey is the public key in the authorized_keys format, for
+	// example "ssh-ed25519 AAAAC3Nz... comment".
+	PublicKey string
+	// Fingerprint is the MD5 fingerprint of the public key, in the format
+	// CircleCI uses, for example "c9:0b:1c:...".
+	Fingerprint string
+}
+
+const ed25519KeyType = "ssh-ed25519"
+
+// GenerateSSHKey generates a new ed25519 key pair. comment is added to the
+// end of the public key, and is usually used to say what the key is for.
+func GenerateSSHKey(comment string) (*SSHKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	var check [4]byte
+	if _, err := rand.Read(check[:]); err != nil {
+		return nil, err
+	}
+	wirePub := marshalED25519PublicKey(pub)
+	return &SSHKey{
+		PrivateKey:  string(marshalED25519PrivateKey(pub, priv, comment, binary.BigEndian.Uint32(check[:]))),
+		PublicKey:   strings.TrimSpace(ed25519KeyType + " " + base64.StdEncoding.EncodeToString(wirePub) + " " + comment),
+		Fingerprint: md5Fingerprint(wirePub),
+	}, nil
+}
+
+// sshBuffer builds the SSH wire encoding described in RFC 4251.
+type sshBuffer []byte
+
+func (b *sshBuffer) uint32(n uint32) {
+	*b = append(*b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func (b *sshBuffer) string(s []byte) {
+	b.uint32(uint32(len(s)))
+	*b = append(*b, s...)
+}
+
+func marshalED25519PublicKey(pub ed25519.PublicKey) []byte {
+	var b sshBuffer
+	b.string([]byte(ed25519KeyType))
+	b.string(pub)
+	return b
+}
+
+// marshalED25519PrivateKey encodes an unencrypted key in the
+// "openssh-key-v1" format described in PROTOCOL.key in the OpenSSH source.
+// check is the random value ssh-keygen uses to detect a wrong passphrase.
+func marshalED25519PrivateKey(pub ed25519.PublicKey, priv ed25519.PrivateKey, comment string, check uint32) []byte {
+	var keys sshBuffer
+	keys.uint32(check)
+	keys.uint32(check)
+	keys.string([]byte(ed25519KeyType))
+	keys.string(pub)
+	keys.string(priv)
+	keys.string([]byte(comment))
+	// Unencrypted keys are padded to a multiple of 8 bytes with 1, 2, 3...
+	for i := byte(1); len(keys)%8 != 0; i++ {
+		keys = append(keys, i)
+	}
+
+	b := sshBuffer("openssh-key-v1\x00")
+	b.string([]byte("none")) // cipher
+	b.string([]byte("none")) // KDF
+	b.string(nil)            // KDF options
+	b.uint32(1)              // number of keys
+	b.string(marshalED25519PublicKey(pub))
+	b.string(keys)
+	return pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: b})
+}
+
+func md5Fingerprint(wirePub []byte) string {
+	sum := md5.Sum(wirePub)
+	parts := make([]string, len(sum))
+	for i, c := range sum {
+		parts[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(parts, ":")
+}
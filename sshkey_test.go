@@ -0,0 +1,123 @@
+package circle
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readSSHString reads a length-prefixed string from the front of b.
+func readSSHString(t *testing.T, b *[]byte) []byte {
+	t.Helper()
+	if len(*b) < 4 {
+		t.Fatalf("short buffer reading length: %d bytes", len(*b))
+	}
+	n := binary.BigEndian.Uint32(*b)
+	if uint32(len(*b)-4) < n {
+		t.Fatalf("short buffer reading %d byte string: %d bytes", n, len(*b)-4)
+	}
+	s := (*b)[4 : 4+n]
+	*b = (*b)[4+n:]
+	return s
+}
+
+func TestGenerateSSHKey(t *testing.T) {
+	key, err := GenerateSSHKey("deploy@go-circle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(key.PublicKey)
+	if len(fields) != 3 || fields[0] != "ssh-ed25519" || fields[2] != "deploy@go-circle" {
+		t.Fatalf("bad public key: %q", key.PublicKey)
+	}
+	wirePub, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(strings.Split(key.Fingerprint, ":")) != 16 {
+		t.Errorf("bad fingerprint: %q", key.Fingerprint)
+	}
+
+	block, rest := pem.Decode([]byte(key.PrivateKey))
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" || len(rest) != 0 {
+		t.Fatalf("bad PEM block: %q", key.PrivateKey)
+	}
+	b := block.Bytes
+	if !bytes.HasPrefix(b, []byte("openssh-key-v1\x00")) {
+		t.Fatalf("missing magic: %q", b)
+	}
+	b = b[len("openssh-key-v1\x00"):]
+	for _, want := range []string{"none", "none", ""} {
+		if got := string(readSSHString(t, &b)); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+	if n := binary.BigEndian.Uint32(b); n != 1 {
+		t.Fatalf("expected 1 key, got %d", n)
+	}
+	b = b[4:]
+	if got := readSSHString(t, &b); !bytes.Equal(got, wirePub) {
+		t.Errorf("public key in the private key doesn't match the public key")
+	}
+	keys := readSSHString(t, &b)
+	if len(b) != 0 || len(keys)%8 != 0 {
+		t.Errorf("expected a padded private section at the end, got %d extra bytes, %d byte section", len(b), len(keys))
+	}
+	if binary.BigEndian.Uint32(keys) != binary.BigEndian.Uint32(keys[4:]) {
+		t.Errorf("check values don't match")
+	}
+	keys = keys[8:]
+	readSSHString(t, &keys)
+	pub := ed25519.PublicKey(readSSHString(t, &keys))
+	priv := ed25519.PrivateKey(readSSHString(t, &keys))
+	if comment := string(readSSHString(t, &keys)); comment != "deploy@go-circle" {
+		t.Errorf("expected comment deploy@go-circle, got %q", comment)
+	}
+	msg := []byte("hello")
+	if !ed25519.Verify(pub, msg, ed25519.Sign(priv, msg)) {
+		t.Errorf("private key doesn't match the public key")
+	}
+}
+
+func TestGenerateSSHKeyOpenSSH(t *testing.T) {
+	keygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	key, err := GenerateSSHKey("deploy@go-circle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "circle-sshkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "id_ed25519")
+	if err := ioutil.WriteFile(path, []byte(key.PrivateKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(keygen, "-y", "-f", path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("ssh-keygen couldn't read the private key: %v: %s", err, out)
+	}
+	// Older versions of ssh-keygen don't print the comment.
+	if got := strings.TrimSpace(string(out)); !strings.HasPrefix(key.PublicKey, got) {
+		t.Errorf("expected ssh-keygen to print %q, got %q", key.PublicKey, got)
+	}
+	out, err = exec.Command(keygen, "-l", "-E", "md5", "-f", path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("ssh-keygen couldn't fingerprint the key: %v: %s", err, out)
+	}
+	if !strings.Contains(string(out), "MD5:"+key.Fingerprint) {
+		t.Errorf("expected fingerprint %s, got %q", key.Fingerprint, out)
+	}
+}
@@ -0,0 +1,178 @@
+package circle
+
+import (
+	"encoding/json"
+)
+
+// Status is the status of a build or an action. Statuses CircleCI adds in the
+// future decode without an error; they're treated as terminal, so code that
+// waits for a build to finish doesn't wait forever.
+type Status string
+
+const (
+	StatusQueued             Status = "queued"
+	StatusScheduled          Status = "scheduled"
+	StatusNotRunning         Status = "not_running"
+	StatusRunning            Status = "running"
+	StatusSuccess            Status = "success"
+	StatusFixed              Status = "fixed"
+	StatusFailed             Status = "failed"
+	StatusTimedout           Status = "timedout"
+	StatusNoTests            Status = "no_tests"
+	StatusInfrastructureFail Status = "infrastructure_fail"
+	StatusCanceled           Status = "canceled"
+	StatusRetried            Status = "retried"
+	StatusNotRun             Status = "not_run"
+)
+
+// IsPending reports whether the build is waiting to start. A build whose
+// status CircleCI hasn't filled in yet is pending.
+func (s Status) IsPending() bool {
+	return s == "" || s == StatusQueued || s == StatusScheduled || s == StatusNotRunning
+}
+
+// IsSuccess reports whether the build passed.
+func (s Status) IsSuccess() bool {
+	return s == StatusSuccess || s == StatusFixed
+}
+
+// IsFailure reports whether the build failed, including failures caused by
+// CircleCI itself.
+func (s Status) IsFailure() bool {
+	switch s {
+	case StatusFailed, StatusTimedout, StatusNoTests, StatusInfrastructureFail:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether the build has stopped, or will never run. Every
+// status except the pending ones and "running" is terminal, including
+// statuses this package doesn't know about.
+func (s Status) IsTerminal() bool {
+	return !s.IsPending() && s != StatusRunning
+}
+
+// IsKnown reports whether s is one of the statuses defined in this package.
+func (s Status) IsKnown() bool {
+	switch s {
+	case StatusQueued, StatusScheduled, StatusNotRunning, StatusRunning,
+		StatusSuccess, StatusFixed, StatusFailed, StatusTimedout, StatusNoTests,
+		StatusInfrastructureFail, StatusCanceled, StatusRetried, StatusNotRun:
+		return true
+	}
+	return false
+}
+
+func (s Status) MarshalJSON() ([]byte, error) {
+	return marshalNullString(string(s))
+}
+
+func (s *Status) UnmarshalJSON(b []byte) error {
+	var str *string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	*s = ""
+	if str != nil {
+		*s = Status(*str)
+	}
+	return nil
+}
+
+// Lifecycle is the stage a build is in. Unknown lifecycles are treated as
+// terminal, like unknown statuses.
+type Lifecycle string
+
+const (
+	LifecycleQueued     Lifecycle = "queued"
+	LifecycleScheduled  Lifecycle = "scheduled"
+	LifecycleNotRunning Lifecycle = "not_running"
+	LifecycleRunning    Lifecycle = "running"
+	LifecycleFinished   Lifecycle = "finished"
+	LifecycleNotRun     Lifecycle = "not_run"
+)
+
+// IsTerminal reports whether the build has stopped, or will never run.
+func (l Lifecycle) IsTerminal() bool {
+	switch l {
+	case LifecycleQueued, LifecycleScheduled, LifecycleNotRunning, LifecycleRunning:
+		return false
+	}
+	return true
+}
+
+func (l Lifecycle) MarshalJSON() ([]byte, error) {
+	return marshalNullString(string(l))
+}
+
+func (l *Lifecycle) UnmarshalJSON(b []byte) error {
+	var str *string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	*l = ""
+	if str != nil {
+		*l = Lifecycle(*str)
+	}
+	return nil
+}
+
+// Outcome is the result of a finished build. It's empty (null in JSON) until
+// the build finishes.
+type Outcome string
+
+const (
+	OutcomeSuccess            Outcome = "success"
+	OutcomeFailed             Outcome = "failed"
+	OutcomeTimedout           Outcome = "timedout"
+	OutcomeNoTests            Outcome = "no_tests"
+	OutcomeInfrastructureFail Outcome = "infrastructure_fail"
+	OutcomeCanceled           Outcome = "canceled"
+)
+
+// IsTerminal reports whether the build has finished, which is true for every
+// non-empty outcome.
+func (o Outcome) IsTerminal() bool {
+	return o != ""
+}
+
+// IsSuccess reports whether the build passed.
+func (o Outcome) IsSuccess() bool {
+	return o == OutcomeSuccess
+}
+
+// IsFailure reports whether the build failed, including failures caused by
+// CircleCI itself.
+func (o Outcome) IsFailure() bool {
+	switch o {
+	case OutcomeFailed, OutcomeTimedout, OutcomeNoTests, OutcomeInfrastructureFail:
+		return true
+	}
+	return false
+}
+
+func (o Outcome) MarshalJSON() ([]byte, error) {
+	return marshalNullString(string(o))
+}
+
+func (o *Outcome) UnmarshalJSON(b []byte) error {
+	var str *string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	*o = ""
+	if str != nil {
+		*o = Outcome(*str)
+	}
+	return nil
+}
+
+// marshalNullString encodes s as a JSON string, or null if it's empty, the way
+// CircleCI does.
+func marshalNullString(s string) ([]byte, error) {
+	if s == "" {
+		return []byte("null"), nil
+	}
+	return json.Marshal(s)
+}
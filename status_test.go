@@ -0,0 +1,92 @@
+package circle
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStatusPredicates(t *testing.T) {
+	tests := []struct {
+		status                              Status
+		pending, success, failure, terminal bool
+	}{
+		{StatusQueued, true, false, false, false},
+		{StatusScheduled, true, false, false, false},
+		{StatusNotRunning, true, false, false, false},
+		{StatusRunning, false, false, false, false},
+		{StatusSuccess, false, true, false, true},
+		{StatusFixed, false, true, false, true},
+		{StatusFailed, false, false, true, true},
+		{StatusTimedout, false, false, true, true},
+		{StatusNoTests, false, false, true, true},
+		{StatusInfrastructureFail, false, false, true, true},
+		{StatusCanceled, false, false, false, true},
+		{StatusRetried, false, false, false, true},
+		{StatusNotRun, false, false, false, true},
+		{Status("some_new_status"), false, false, false, true},
+		{Status(""), true, false, false, false},
+	}
+	for _, tt := range tests {
+		s := tt.status
+		if s.IsPending() != tt.pending || s.IsSuccess() != tt.success || s.IsFailure() != tt.failure || s.IsTerminal() != tt.terminal {
+			t.Errorf("%s: got pending=%t success=%t failure=%t terminal=%t", s, s.IsPending(), s.IsSuccess(), s.IsFailure(), s.IsTerminal())
+		}
+		if known := s != "some_new_status" && s != ""; s.IsKnown() != known {
+			t.Errorf("%s: expected IsKnown to be %t", s, known)
+		}
+	}
+}
+
+func TestActionFailed(t *testing.T) {
+	for _, status := range []Status{StatusFailed, StatusTimedout, StatusInfrastructureFail} {
+		a := &Action{Status: status}
+		if !a.Failed() {
+			t.Errorf("expected an action with status %s to be failed", status)
+		}
+	}
+	if a := (&Action{Status: StatusCanceled}); a.Failed() {
+		t.Error("expected a canceled action not to be failed")
+	}
+}
+
+func TestOutcomeAndLifecycle(t *testing.T) {
+	if Outcome("").IsTerminal() || !OutcomeCanceled.IsTerminal() {
+		t.Error("expected only non-empty outcomes to be terminal")
+	}
+	if !OutcomeSuccess.IsSuccess() || !OutcomeInfrastructureFail.IsFailure() || OutcomeCanceled.IsFailure() {
+		t.Error("bad outcome predicates")
+	}
+	if LifecycleRunning.IsTerminal() || !LifecycleFinished.IsTerminal() || !LifecycleNotRun.IsTerminal() {
+		t.Error("bad lifecycle predicates")
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	type build struct {
+		Status    Status    `json:"status"`
+		Lifecycle Lifecycle `json:"lifecycle"`
+		Outcome   Outcome   `json:"outcome"`
+	}
+	tests := []string{
+		`{"status":"running","lifecycle":"running","outcome":null}`,
+		`{"status":"fixed","lifecycle":"finished","outcome":"success"}`,
+		`{"status":"some_new_status","lifecycle":"some_new_lifecycle","outcome":"some_new_outcome"}`,
+	}
+	for _, in := range tests {
+		var b build
+		if err := json.Unmarshal([]byte(in), &b); err != nil {
+			t.Fatal(err)
+		}
+		out, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != in {
+			t.Errorf("round trip: got %s, want %s", out, in)
+		}
+	}
+	var b build
+	if err := json.Unmarshal([]byte(`{"status":5}`), &b); err == nil {
+		t.Error("expected an error decoding a number as a status")
+	}
+}
@@ -0,0 +1,66 @@
+package circle
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TestResult is the result of a single test, parsed by CircleCI from the
+// JUnit XML files a build stores with store_test_results.
+type TestResult struct {
+	Name      string `json:"name"`
+	Classname string `json:"classname"`
+	File      string `json:"file"`
+	// Result is "success", "failure", "error" or "skipped".
+	Result string `json:"result"`
+	// RunTime is the number of seconds the test took.
+	RunTime float64 `json:"run_time"`
+	// Message is the failure message, if the test failed.
+	Message    string `json:"message"`
+	Source     string `json:"source"`      // "go-test", "mocha", ...
+	SourceType string `json:"source_type"` // "test_results"
+}
+
+// Failed reports whether the test failed or errored.
+func (tr *TestResult) Failed() bool {
+	return tr.Result == "failure" || tr.Result == "error"
+}
+
+// Duration returns how long the test took.
+func (tr *TestResult) Duration() time.Duration {
+	return time.Duration(tr.RunTime * float64(time.Second))
+}
+
+// TestMetadata is the list of test results for a build.
+type TestMetadata struct {
+	Tests []*TestResult `json:"tests"`
+}
+
+// Failures returns the tests that failed, in the order CircleCI returned
+// them.
+func (tm *TestMetadata) Failures() []*TestResult {
+	var failed []*TestResult
+	for _, tr := range tm.Tests {
+		if tr.Failed() {
+			failed = append(failed, tr)
+		}
+	}
+	return failed
+}
+
+// GetTestMetadata returns the test results for a build. The list is empty if
+// the build didn't store any test results. vcsType is "github" or
+// "bitbucket".
+func (c *Client) GetTestMetadata(ctx context.Context, vcsType, org, project string, buildNum int) (*TestMetadata, error) {
+	uri := fmt.Sprintf("%s/%d/tests", getProjectUri(vcsType, org, project), buildNum)
+	tm := new(TestMetadata)
+	if err := c.get(ctx, org, uri, tm); err != nil {
+		return nil, err
+	}
+	return tm, nil
+}
+
+func GetTestMetadata(ctx context.Context, vcsType, org, project string, buildNum int) (*TestMetadata, error) {
+	return DefaultClient.GetTestMetadata(ctx, vcsType, org, project, buildNum)
+}
@@ -0,0 +1,44 @@
+package circle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func TestGetTestMetadata(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{
+		Status: "failed",
+		Tests: []circletest.TestResult{
+			{Name: "TestBuild", Classname: "github.com/Shyp/go-circle", Result: "success", RunTime: 20 * time.Millisecond},
+			{Name: "TestWait", Classname: "github.com/Shyp/go-circle/wait", File: "wait/wait_test.go", Result: "failure", RunTime: 1500 * time.Millisecond, Message: "wait_test.go:12: expected success"},
+			{Name: "TestSkipped", Classname: "github.com/Shyp/go-circle", Result: "skipped"},
+		},
+	})
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("token"), Retry: NoRetries}
+	tm, err := c.GetTestMetadata(context.Background(), "github", "Shyp", "go-circle", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tm.Tests) != 3 {
+		t.Fatalf("expected 3 tests, got %d", len(tm.Tests))
+	}
+	if tm.Tests[0].Message != "" {
+		t.Errorf("expected a null message to decode as empty, got %q", tm.Tests[0].Message)
+	}
+	failed := tm.Failures()
+	if len(failed) != 1 {
+		t.Fatalf("expected 1 failed test, got %d", len(failed))
+	}
+	tr := failed[0]
+	if tr.Name != "TestWait" || tr.File != "wait/wait_test.go" || tr.Message != "wait_test.go:12: expected success" {
+		t.Errorf("bad failed test: %#v", tr)
+	}
+	if d := tr.Duration(); d != 1500*time.Millisecond {
+		t.Errorf("expected a duration of 1.5s, got %s", d)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/BurntSushi/toml"
 )
@@ -42,7 +43,49 @@ Go to https://circleci.com/account/api if you need to create a token.
 	}
 }
 
-func getToken(orgName string) (string, error) {
+// A TokenSource returns the CircleCI API token to use for requests made on
+// behalf of the given organization.
+type TokenSource interface {
+	Token(org string) (string, error)
+}
+
+// StaticToken is a TokenSource that returns the same token for every
+// organization.
+type StaticToken string
+
+func (s StaticToken) Token(org string) (string, error) {
+	return string(s), nil
+}
+
+// ConfigTokenSource is a TokenSource that reads tokens from the TOML
+// configuration file described in the README. The file is read the first time
+// a token is requested, and cached for the lifetime of the ConfigTokenSource.
+//
+// The zero value is ready to use.
+type ConfigTokenSource struct {
+	mu  sync.Mutex
+	cfg *CircleConfig
+}
+
+func (c *ConfigTokenSource) Token(org string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cfg == nil {
+		cfg, err := loadConfig()
+		if err != nil {
+			return "", err
+		}
+		c.cfg = cfg
+	}
+	o, err := getCaseInsensitiveOrg(org, c.cfg.Organizations)
+	if err != nil {
+		return "", err
+	}
+	return o.Token, nil
+}
+
+// loadConfig finds and parses the CircleCI configuration file.
+func loadConfig() (*CircleConfig, error) {
 	var filename string
 	var f io.ReadCloser
 	var err error
@@ -80,17 +123,12 @@ Add a configuration file with your CircleCI token, like this:
 
 Go to https://circleci.com/account/api if you need to create a token.
 `, strings.Join(checkedLocations, " or "))
-		return "", err
+		return nil, err
 	}
 	defer f.Close()
-	var c CircleConfig
-	_, err = toml.DecodeReader(bufio.NewReader(f), &c)
-	if err != nil {
-		return "", err
-	}
-	org, err := getCaseInsensitiveOrg(orgName, c.Organizations)
-	if err != nil {
-		return "", err
+	c := new(CircleConfig)
+	if _, err := toml.DecodeReader(bufio.NewReader(f), c); err != nil {
+		return nil, err
 	}
-	return org.Token, nil
+	return c, nil
 }
@@ -13,7 +13,7 @@ func TestCaseInsensitive(t *testing.T) {
 	}
 	o, err := getCaseInsensitiveOrg("ShyP", cfg.Organizations)
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	if o.Token != "foo" {
 		t.Fatalf("expected o.Token to be foo, was %v", o.Token)
@@ -0,0 +1,93 @@
+package circle
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// maxLoggedBody is the most of a request or response body that is logged when
+// Client.LogBodies is true.
+const maxLoggedBody = 16 * 1024
+
+// RetryLogMessage is the message of the record a Client logs before it retries
+// a request. The record for the exchange itself is logged once the request
+// finishes, with the number of retries it took.
+const RetryLogMessage = "retrying circle request"
+
+// NewDebugLogger returns a Logger that writes human readable records for
+// every level to w.
+func NewDebugLogger(w io.Writer) log.Logger {
+	l := log.New()
+	l.SetHandler(log.StreamHandler(w, log.LogfmtFormat()))
+	return l
+}
+
+// debugFromEnv configures c to log requests and bodies to stderr if the
+// CIRCLE_DEBUG environment variable is "true".
+func debugFromEnv(c *Client) {
+	if os.Getenv("CIRCLE_DEBUG") == "true" {
+		c.Logger = NewDebugLogger(os.Stderr)
+		c.LogBodies = true
+	}
+}
+
+type bodyReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// peekBody reads up to maxLoggedBody bytes from rc, and returns them along with
+// a ReadCloser that yields the entire body.
+func peekBody(rc io.ReadCloser) ([]byte, io.ReadCloser) {
+	peek, _ := ioutil.ReadAll(io.LimitReader(rc, maxLoggedBody))
+	return peek, bodyReadCloser{io.MultiReader(bytes.NewReader(peek), rc), rc}
+}
+
+// logExchange records a request and its outcome on c.Logger. If c.LogBodies
+// is true, the start of the response body is logged too, and logExchange
+// returns a response whose body can still be read in full.
+func (c *Client) logExchange(req *http.Request, resp *http.Response, err error, attempts int, elapsed time.Duration) *http.Response {
+	ctx := []interface{}{
+		"method", req.Method,
+		"host", req.URL.Host,
+		"path", c.secrets.redact(redactedPath(req.URL)),
+		"retries", attempts - 1,
+		"duration_ms", int64(elapsed / time.Millisecond),
+	}
+	if org := requestOrg(req); org != "" {
+		ctx = append(ctx, "org", org)
+	}
+	if c.LogBodies && hasSecretBody(req) {
+		ctx = append(ctx, "request_body", redacted)
+	} else if c.LogBodies && req.GetBody != nil {
+		if body, berr := req.GetBody(); berr == nil {
+			peek, _ := ioutil.ReadAll(io.LimitReader(body, maxLoggedBody))
+			body.Close()
+			ctx = append(ctx, "request_body", c.secrets.redact(string(peek)))
+		}
+	}
+	if err != nil {
+		var cerr *Error
+		if errors.As(err, &cerr) {
+			ctx = append(ctx, "status", cerr.StatusCode)
+		}
+		ctx = append(ctx, "err", err.Error())
+		c.Logger.Warn("circle request failed", ctx...)
+		return resp
+	}
+	ctx = append(ctx, "status", resp.StatusCode)
+	if c.LogBodies {
+		var peek []byte
+		peek, resp.Body = peekBody(resp.Body)
+		ctx = append(ctx, "body", c.secrets.redact(string(peek)))
+	}
+	c.Logger.Debug("circle request", ctx...)
+	return resp
+}
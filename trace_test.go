@@ -0,0 +1,100 @@
+package circle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+)
+
+func recordLogger(records *[]*log.Record) log.Logger {
+	l := log.New()
+	l.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		*records = append(*records, r)
+		return nil
+	}))
+	return l
+}
+
+func ctxValue(r *log.Record, key string) interface{} {
+	for i := 0; i+1 < len(r.Ctx); i += 2 {
+		if r.Ctx[i] == key {
+			return r.Ctx[i+1]
+		}
+	}
+	return nil
+}
+
+func TestLogExchange(t *testing.T) {
+	var count int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"build_num": 5, "subject": "token is s3cret"}`))
+	}))
+	defer s.Close()
+	var records []*log.Record
+	c := &Client{
+		BaseURL:   s.URL,
+		Tokens:    StaticToken("s3cret"),
+		Logger:    recordLogger(&records),
+		LogBodies: true,
+		Retry:     &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond},
+	}
+	cb, err := c.GetBuild(context.Background(), "Shyp", "go-circle", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.BuildNum != 5 {
+		t.Errorf("body was not readable after logging: %#v", cb)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected a retry record and a request record, got %d", len(records))
+	}
+	r := records[1]
+	if r.Msg != "circle request" {
+		t.Errorf("unexpected message %q", r.Msg)
+	}
+	if got := ctxValue(r, "status"); got != 200 {
+		t.Errorf("expected status 200, got %v", got)
+	}
+	if got := ctxValue(r, "retries"); got != 1 {
+		t.Errorf("expected 1 retry, got %v", got)
+	}
+	if got := ctxValue(r, "path"); got != "/v1/project/Shyp/go-circle/5" {
+		t.Errorf("bad path: %v", got)
+	}
+	body, _ := ctxValue(r, "body").(string)
+	if !strings.Contains(body, "token is REDACTED") {
+		t.Errorf("expected body to be logged with the token redacted, got %q", body)
+	}
+}
+
+func TestLogExchangeError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte(`{"message": "Build not found"}`))
+	}))
+	defer s.Close()
+	var records []*log.Record
+	c := &Client{BaseURL: s.URL, Tokens: StaticToken("s3cret"), Logger: recordLogger(&records)}
+	if _, err := c.GetBuild(context.Background(), "Shyp", "go-circle", 5); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected one record, got %d", len(records))
+	}
+	if records[0].Lvl != log.LvlWarn {
+		t.Errorf("expected a warning, got %v", records[0].Lvl)
+	}
+	if got := ctxValue(records[0], "status"); got != 404 {
+		t.Errorf("expected status 404, got %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package circle
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig holds the connection pool and timeout settings for the
+// transport returned by NewTransport.
+type TransportConfig struct {
+	// MaxIdleConns is the maximum number of idle connections across all
+	// hosts. MaxIdleConnsPerHost limits them for each host; most requests go
+	// to circleci.com, so it should be close to MaxIdleConns.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection stays in the pool.
+	IdleConnTimeout time.Duration
+
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+
+	// DisableHTTP2 turns off HTTP/2, which is negotiated by default.
+	DisableHTTP2 bool
+
+	// TLSClientConfig, if set, is used for TLS connections, for example to
+	// trust a test server's certificate.
+	TLSClientConfig *tls.Config
+}
+
+// DefaultTransportConfig keeps connections open for long enough that
+// a "circle wait" session reuses one connection for its whole run.
+var DefaultTransportConfig = TransportConfig{
+	MaxIdleConns:          32,
+	MaxIdleConnsPerHost:   DefaultConcurrency * 2,
+	IdleConnTimeout:       2 * time.Minute,
+	DialTimeout:           10 * time.Second,
+	KeepAlive:             30 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: 30 * time.Second,
+}
+
+// NewTransport returns an *http.Transport configured with cfg. A transport
+// should be shared by every Client in the process, so they can reuse
+// connections.
+func NewTransport(cfg TransportConfig) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig:       cfg.TLSClientConfig,
+	}
+}
+
+// DefaultTransport is shared by DefaultClient and every Client created with
+// NewClient.
+var DefaultTransport = NewTransport(DefaultTransportConfig)
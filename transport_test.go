@@ -0,0 +1,95 @@
+package circle
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Shyp/go-circle/circletest"
+)
+
+func TestConnectionReuse(t *testing.T) {
+	var conns int32
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A trailing newline after the JSON body, like CircleCI sends.
+		w.Write([]byte("[]\n"))
+	}))
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	s.Start()
+	defer s.Close()
+	c := &Client{
+		BaseURL:    s.URL,
+		Tokens:     StaticToken("token"),
+		HTTPClient: &http.Client{Transport: NewTransport(DefaultTransportConfig)},
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := c.GetTree(context.Background(), "Shyp", "go-circle", "master"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("expected 10 requests to share 1 connection, opened %d", n)
+	}
+}
+
+func newBenchServer(b *testing.B) (*circletest.Server, TransportConfig) {
+	s := circletest.NewTLSServer()
+	for i := 0; i < 20; i++ {
+		s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "success"})
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	cfg := DefaultTransportConfig
+	cfg.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return s, cfg
+}
+
+// BenchmarkGetTreeSharedTransport polls the tree the way "circle wait" does,
+// reusing one connection.
+func BenchmarkGetTreeSharedTransport(b *testing.B) {
+	s, cfg := newBenchServer(b)
+	defer s.Close()
+	c := &Client{
+		BaseURL:    s.URL,
+		Tokens:     StaticToken("token"),
+		HTTPClient: &http.Client{Transport: NewTransport(cfg)},
+	}
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.GetTree(ctx, "Shyp", "go-circle", "master"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetTreeNewTransport pays for a new connection and TLS handshake
+// on every request, as the client did when it created a new HTTP client per
+// call.
+func BenchmarkGetTreeNewTransport(b *testing.B) {
+	s, cfg := newBenchServer(b)
+	defer s.Close()
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr := NewTransport(cfg)
+		c := &Client{
+			BaseURL:    s.URL,
+			Tokens:     StaticToken("token"),
+			HTTPClient: &http.Client{Transport: tr},
+		}
+		if _, err := c.GetTree(ctx, "Shyp", "go-circle", "master"); err != nil {
+			b.Fatal(err)
+		}
+		tr.CloseIdleConnections()
+	}
+}
@@ -1,10 +1,10 @@
 package wait
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"net"
-	"net/url"
+	"strings"
 	"time"
 
 	"github.com/Shyp/go-circle"
@@ -37,29 +37,6 @@ func getEffectiveCost(d time.Duration) int {
 	return round(salaryPerHour)
 }
 
-// isHttpError checks if the given error is a request timeout or a network
-// failure - in those cases we want to just retry the request.
-func isHttpError(err error) bool {
-	if err == nil {
-		return false
-	}
-	// some net.OpError's are wrapped in a url.Error
-	if uerr, ok := err.(*url.Error); ok {
-		err = uerr.Err
-	}
-	switch err := err.(type) {
-	default:
-		return false
-	case *net.OpError:
-		return err.Op == "dial" && err.Net == "tcp"
-	case *net.DNSError:
-		return true
-	// Catchall, this needs to go last.
-	case net.Error:
-		return err.Timeout() || err.Temporary()
-	}
-}
-
 // getMinTipLength compares two git hashes and returns the length of the
 // shortest
 func getMinTipLength(remoteTip string, localTip string) int {
@@ -72,7 +49,19 @@ func getMinTipLength(remoteTip string, localTip string) int {
 	return minTipLength
 }
 
+// sleep is replaced in tests so they don't have to wait for real.
+var sleep = sleepContext
+
+// Wait polls CircleCI until every workflow in the pipeline for the tip of
+// branch completes, then prints statistics about each job. Wait returns an
+// error unless every workflow succeeded.
 func Wait(branch string) error {
+	return WaitContext(context.Background(), branch)
+}
+
+// WaitContext is like Wait, but stops waiting and returns ctx.Err() when ctx
+// is canceled.
+func WaitContext(ctx context.Context, branch string) error {
 	remote, err := git.GetRemoteURL("origin")
 	if err != nil {
 		return err
@@ -81,128 +70,283 @@ func Wait(branch string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("Waiting for latest build on", branch, "to complete")
-	// Give CircleCI a little bit of time to start
-	time.Sleep(1 * time.Second)
+	vcsType, err := circle.VCSType(remote.Host)
+	if err != nil {
+		return err
+	}
+	return waitForPipeline(ctx, circle.DefaultClient, vcsType, remote.Path, remote.RepoName, branch, tip)
+}
+
+// WaitForBuild polls CircleCI until the build with the given number finishes,
+// then prints statistics about it. WaitForBuild returns an error if the build
+// didn't pass.
+func WaitForBuild(ctx context.Context, org, repoName string, buildNum int) error {
+	return waitForBuildNum(ctx, circle.DefaultClient, org, repoName, buildNum)
+}
+
+// retryWait waits a little and returns nil if err is a network error worth
+// retrying, and returns err otherwise.
+func retryWait(ctx context.Context, err error) error {
+	if circle.IsRetryable(err) && ctx.Err() == nil {
+		fmt.Printf("Caught network error: %s. Continuing\n", err.Error())
+		return sleep(ctx, 2*time.Second)
+	}
+	return err
+}
+
+// waitForBuild polls the latest build on branch until it is the build of tip,
+// and then until it finishes.
+func waitForBuild(ctx context.Context, client *circle.Client, org, repoName, branch, tip string) error {
 	for {
-		cr, err := circle.GetTree(remote.Path, remote.RepoName, branch)
+		cr, err := client.GetTree(ctx, org, repoName, branch)
 		if err != nil {
-			if isHttpError(err) {
-				fmt.Printf("Caught network error: %s. Continuing\n", err.Error())
-				time.Sleep(2 * time.Second)
-				continue
+			if err := retryWait(ctx, err); err != nil {
+				return err
 			}
-			return err
+			continue
 		}
 		if len(*cr) == 0 {
 			return fmt.Errorf("No results, are you sure there are tests for %s/%s?\n",
-				remote.Path, remote.RepoName)
+				org, repoName)
 		}
 		latestBuild := (*cr)[0]
 		maxTipLengthToCompare := getMinTipLength(latestBuild.VCSRevision, tip)
 		if latestBuild.VCSRevision[:maxTipLengthToCompare] != tip[:maxTipLengthToCompare] {
 			fmt.Printf("Latest build in Circle is %s, waiting for %s...\n",
 				latestBuild.VCSRevision[:maxTipLengthToCompare], tip[:maxTipLengthToCompare])
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		var duration time.Duration
-		if latestBuild.QueuedAt.Valid {
-			if latestBuild.StopTime.Valid {
-				duration = latestBuild.StopTime.Time.Sub(latestBuild.QueuedAt.Time)
-			} else {
-				duration = time.Since(latestBuild.QueuedAt.Time)
-			}
-		} else if latestBuild.UsageQueuedAt.Valid {
-			if latestBuild.StopTime.Valid {
-				duration = latestBuild.StopTime.Time.Sub(latestBuild.UsageQueuedAt.Time)
-			} else {
-				duration = time.Since(latestBuild.UsageQueuedAt.Time)
+			if err := sleep(ctx, 5*time.Second); err != nil {
+				return err
 			}
+			continue
 		}
-		duration = roundDuration(duration, time.Second)
-		if latestBuild.Passed() {
-			fmt.Printf("Build on %s succeeded!\n\n", branch)
-			build, err := circle.GetBuild(remote.Path, remote.RepoName, latestBuild.BuildNum)
-			if err == nil {
-				fmt.Print(build.Statistics())
-			} else {
-				fmt.Printf("error getting build: %v\n", err)
-			}
-			fmt.Printf("\nTests on %s took %s. Quitting.\n", branch, duration.String())
-			c := bigtext.Client{
-				Name:    fmt.Sprintf("%s (go-circle)", remote.RepoName),
-				OpenURL: latestBuild.BuildURL,
-			}
-			c.Display(branch + " build complete!")
-			break
-		} else if latestBuild.Failed() {
-			build, err := circle.GetBuild(remote.Path, remote.RepoName, latestBuild.BuildNum)
-			if err == nil {
-				fmt.Print(build.Statistics())
-				texts, textsErr := build.FailureTexts(context.Background())
-				if textsErr != nil {
-					fmt.Printf("error getting build failures: %v\n", textsErr)
-				}
-				fmt.Printf("\nOutput from failed builds:\n\n")
-				for _, text := range texts {
-					fmt.Println(text)
-				}
-			} else {
+		duration := roundDuration(latestBuild.TotalDuration(), time.Second)
+		if latestBuild.Status.IsTerminal() {
+			build, err := client.GetBuild(ctx, org, repoName, latestBuild.BuildNum)
+			if err != nil {
 				fmt.Printf("error getting build: %v\n", err)
+				build = nil
 			}
-			fmt.Printf("\nURL: %s\n", latestBuild.BuildURL)
-			err = fmt.Errorf("Build on %s failed!\n\n", branch)
-			c := bigtext.Client{
-				Name:    fmt.Sprintf("%s (go-circle)", remote.RepoName),
-				OpenURL: latestBuild.BuildURL,
+			return reportBuild(ctx, client, repoName, branch, latestBuild.Status, latestBuild.BuildURL, build, duration)
+		}
+		printProgress(latestBuild.Status, duration)
+		if err := sleep(ctx, pollInterval(latestBuild.Previous, duration)); err != nil {
+			return err
+		}
+	}
+}
+
+func waitForBuildNum(ctx context.Context, client *circle.Client, org, repoName string, buildNum int) error {
+	fmt.Printf("Waiting for build %d to complete\n", buildNum)
+	for {
+		cb, err := client.GetBuild(ctx, org, repoName, buildNum)
+		if err != nil {
+			if err := retryWait(ctx, err); err != nil {
+				return err
 			}
-			c.Display("build failed")
+			continue
+		}
+		duration := roundDuration(cb.TotalDuration(), time.Second)
+		if cb.Status.IsTerminal() {
+			return reportBuild(ctx, client, repoName, cb.Branch, cb.Status, cb.BuildURL, cb, duration)
+		}
+		printProgress(cb.Status, duration)
+		if err := sleep(ctx, pollInterval(cb.Previous, duration)); err != nil {
 			return err
-		} else {
-			if latestBuild.Status == "running" {
-				fmt.Printf("Running (%s elapsed)\n", duration.String())
-			} else if latestBuild.NotRunning() {
-				cost := getEffectiveCost(duration)
-				centsPortion := cost % 100
-				dollarPortion := cost / 100
-				costStr := fmt.Sprintf("$%d.%.2d", dollarPortion, centsPortion)
-				fmt.Printf("Status is %s (queued for %s, cost %s), trying again\n",
-					latestBuild.Status, duration.String(), costStr)
-			} else {
-				fmt.Printf("Status is %s, trying again\n", latestBuild.Status)
+		}
+	}
+}
+
+// printProgress prints the status of a build that hasn't finished yet.
+func printProgress(status circle.Status, duration time.Duration) {
+	if status == circle.StatusRunning {
+		fmt.Printf("Running (%s elapsed)\n", duration.String())
+		return
+	}
+	cost := getEffectiveCost(duration)
+	centsPortion := cost % 100
+	dollarPortion := cost / 100
+	costStr := fmt.Sprintf("$%d.%.2d", dollarPortion, centsPortion)
+	fmt.Printf("Status is %s (queued for %s, cost %s), trying again\n",
+		status, duration.String(), costStr)
+}
+
+// reportBuild prints the statistics of a finished build, and its failures if
+// it failed, and returns an error unless it passed. build is nil if it
+// couldn't be fetched.
+func reportBuild(ctx context.Context, client *circle.Client, repoName, branch string, status circle.Status, buildURL string, build *circle.CircleBuild, duration time.Duration) error {
+	c := bigtext.Client{
+		Name:    fmt.Sprintf("%s (go-circle)", repoName),
+		OpenURL: buildURL,
+	}
+	switch {
+	case status.IsSuccess():
+		fmt.Printf("Build on %s succeeded!\n\n", branch)
+		if build != nil {
+			fmt.Print(build.Statistics())
+		}
+		fmt.Printf("\nTests on %s took %s. Quitting.\n", branch, duration.String())
+		c.Display(branch + " build complete!")
+		return nil
+	case status.IsFailure():
+		if build != nil {
+			fmt.Print(build.Statistics())
+			printFailures(ctx, client, build)
+		}
+		fmt.Printf("\nURL: %s\n", buildURL)
+		c.Display("build failed")
+		return fmt.Errorf("Build on %s failed!\n\n", branch)
+	}
+	// Canceled, retried or not run, or a status we don't know about; either
+	// way the build isn't going to change.
+	fmt.Printf("\nURL: %s\n", buildURL)
+	c.Display("build " + string(status))
+	return fmt.Errorf("Build on %s finished with status %s\n\n", branch, status)
+}
+
+// maxMessageLines is the number of lines of each failure message
+// formatFailedTests prints.
+const maxMessageLines = 10
+
+// formatFailedTests returns a summary of the failed tests, with the start of
+// each test's failure message.
+func formatFailedTests(failed []*circle.TestResult) string {
+	var buf bytes.Buffer
+	if len(failed) == 1 {
+		buf.WriteString("\n1 failed test:\n\n")
+	} else {
+		fmt.Fprintf(&buf, "\n%d failed tests:\n\n", len(failed))
+	}
+	for _, tr := range failed {
+		name := tr.Name
+		if tr.Classname != "" {
+			name = tr.Classname + " " + tr.Name
+		}
+		fmt.Fprintf(&buf, "%s (%s)\n", name, roundDuration(tr.Duration(), time.Millisecond))
+		lines := strings.Split(strings.TrimRight(tr.Message, "\n"), "\n")
+		if len(lines) == 1 && lines[0] == "" {
+			lines = nil
+		}
+		for i, line := range lines {
+			if i == maxMessageLines {
+				fmt.Fprintf(&buf, "    ... %d more lines\n", len(lines)-maxMessageLines)
+				break
 			}
-			// Sleep less and less as we approach the duration of the previous
-			// successful build
-			buildDuration := time.Duration(latestBuild.Previous.BuildDurationMs) * time.Millisecond
-			if latestBuild.Previous.Status == "success" || latestBuild.Previous.Status == "fixed" {
-				if duration < time.Minute {
-					// First minute, errors are slightly more likely.
-					time.Sleep(5 * time.Second)
-				} else {
-					timeRemaining := buildDuration - duration
-					if timeRemaining > 5*time.Minute {
-						time.Sleep(30 * time.Second)
-					} else if timeRemaining > 3*time.Minute {
-						time.Sleep(20 * time.Second)
-					} else if timeRemaining > time.Minute {
-						time.Sleep(15 * time.Second)
-					} else if timeRemaining > 30*time.Second {
-						time.Sleep(10 * time.Second)
-					} else if timeRemaining > 10*time.Second {
-						time.Sleep(5 * time.Second)
-					} else {
-						time.Sleep(3 * time.Second)
-					}
-				}
-			} else {
-				if float32(duration) < (2.5 * float32(time.Minute)) {
-					time.Sleep(10 * time.Second)
-				} else {
-					time.Sleep(5 * time.Second)
-				}
+			fmt.Fprintf(&buf, "    %s\n", line)
+		}
+	}
+	return buf.String()
+}
+
+// printFailedTests prints the tests that failed in build, and reports whether
+// there were any. Builds that don't store test results have none, so the
+// caller should print the output of the failed steps instead. Errors getting
+// the results are treated the same way; the client's logger records them, if
+// it's set.
+func printFailedTests(ctx context.Context, client *circle.Client, build *circle.CircleBuild) bool {
+	tm, err := client.GetTestMetadata(ctx, build.VCSType, build.Username, build.RepoName, int(build.BuildNum))
+	if err != nil {
+		return false
+	}
+	failed := tm.Failures()
+	if len(failed) == 0 {
+		return false
+	}
+	fmt.Print(formatFailedTests(failed))
+	return true
+}
+
+// printFailures prints the tests that failed in build, or the output of its
+// failed steps if it doesn't have test results.
+func printFailures(ctx context.Context, client *circle.Client, build *circle.CircleBuild) {
+	if printFailedTests(ctx, client, build) {
+		return
+	}
+	texts, err := client.FailureTexts(ctx, build)
+	if err != nil {
+		fmt.Printf("error getting build failures: %v\n", err)
+	}
+	fmt.Printf("\nOutput from failed builds:\n\n")
+	for _, text := range texts {
+		fmt.Println(text)
+	}
+}
+
+// WaitForSSH polls a build that was retried with SSH enabled until it prints
+// how to log in, and returns an ssh command for each container. WaitForSSH
+// returns an error if the build finishes before SSH is enabled.
+func WaitForSSH(ctx context.Context, org, repoName string, buildNum int) ([]string, error) {
+	return waitForSSH(ctx, circle.DefaultClient, org, repoName, buildNum)
+}
+
+func waitForSSH(ctx context.Context, client *circle.Client, org, repoName string, buildNum int) ([]string, error) {
+	for {
+		cb, err := client.GetBuild(ctx, org, repoName, buildNum)
+		var commands []string
+		if err == nil {
+			commands, err = client.SSHCommands(ctx, cb)
+		}
+		if err != nil {
+			if err := retryWait(ctx, err); err != nil {
+				return nil, err
 			}
+			continue
+		}
+		if len(commands) > 0 {
+			return commands, nil
+		}
+		if cb.Status.IsTerminal() {
+			return nil, fmt.Errorf("Build %d finished with status %s before SSH was enabled", buildNum, cb.Status)
+		}
+		fmt.Printf("Status is %s, waiting for SSH details...\n", cb.Status)
+		if err := sleep(ctx, 5*time.Second); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+}
+
+// pollInterval returns how long to wait before checking on a running build
+// again, given the previous build on the branch.
+func pollInterval(previous circle.PreviousBuild, duration time.Duration) time.Duration {
+	if previous.Status.IsSuccess() {
+		return remainingInterval(time.Duration(previous.BuildDurationMs)*time.Millisecond, duration)
+	}
+	if float32(duration) < (2.5 * float32(time.Minute)) {
+		return 10 * time.Second
+	}
+	return 5 * time.Second
+}
+
+// remainingInterval returns how long to wait before checking on a run that
+// has taken duration so far, and that is expected to take expected, like the
+// previous successful run. We sleep less and less as we approach expected.
+func remainingInterval(expected, duration time.Duration) time.Duration {
+	if duration < time.Minute {
+		// First minute, errors are slightly more likely.
+		return 5 * time.Second
+	}
+	timeRemaining := expected - duration
+	if timeRemaining > 5*time.Minute {
+		return 30 * time.Second
+	} else if timeRemaining > 3*time.Minute {
+		return 20 * time.Second
+	} else if timeRemaining > time.Minute {
+		return 15 * time.Second
+	} else if timeRemaining > 30*time.Second {
+		return 10 * time.Second
+	} else if timeRemaining > 10*time.Second {
+		return 5 * time.Second
+	}
+	return 3 * time.Second
+}
+
+// sleepContext waits for d, or until ctx is canceled, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
@@ -1,40 +1,18 @@
 package wait
 
 import (
-	"io"
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
-)
-
-func makeRequest(client http.Client, method, uri string) (io.ReadCloser, error) {
-	req, err := http.NewRequest(method, uri, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("User-Agent", "wait-test")
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	return resp.Body, nil
-}
-
-func TestHttpError(t *testing.T) {
-	client := http.Client{
-		Timeout: 200 * time.Millisecond,
-	}
-	_, err := makeRequest(client, "GET", "http://localhost:11233")
-	if !isHttpError(err) {
-		t.Fatalf("expected err to be http error, was %s", err)
-	}
 
-	_, err = makeRequest(client, "GET", "https://httpbin.org/delay/2")
-	if !isHttpError(err) {
-		t.Fatalf("expected err to be http error, was %s", err)
-	}
-}
+	circle "github.com/Shyp/go-circle"
+	"github.com/Shyp/go-circle/circletest"
+	"github.com/Shyp/go-circle/circlev2"
+)
 
 func TestEffectiveCost(t *testing.T) {
 	cost := getEffectiveCost(1 * time.Hour)
@@ -70,3 +48,382 @@ func TestGetMaxTipLength(t *testing.T) {
 		t.Errorf("expected half hour cost to be %d, was %d", expectedMinTipLength, minTipLength)
 	}
 }
+
+func newTestClient(s *circletest.Server) *circle.Client {
+	return &circle.Client{
+		BaseURL: s.URL,
+		Tokens:  circle.StaticToken("token"),
+	}
+}
+
+// maxSleeps is how many times a test can sleep before we decide the wait is
+// stuck.
+const maxSleeps = 100
+
+// panicSleep stands in for sleep in tests that haven't called stubSleep, so
+// they can't sleep for real.
+func panicSleep(ctx context.Context, d time.Duration) error {
+	panic("sleep called without stubSleep")
+}
+
+func init() {
+	sleep = panicSleep
+}
+
+// stubSleep replaces sleep for the rest of the test with a function that
+// returns at once, and returns the durations it was asked to sleep for. If
+// tick is not nil, it's called with the number of sleeps so far after each
+// one, in place of time passing. A wait that sleeps more than maxSleeps times
+// fails the test.
+func stubSleep(t *testing.T, tick func(n int)) *[]time.Duration {
+	t.Helper()
+	var mu sync.Mutex
+	var durations []time.Duration
+	sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		durations = append(durations, d)
+		n := len(durations)
+		mu.Unlock()
+		if n > maxSleeps {
+			t.Errorf("slept more than %d times, the wait is stuck", maxSleeps)
+			return errors.New("too many sleeps")
+		}
+		if tick != nil {
+			tick(n)
+		}
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = panicSleep })
+	return &durations
+}
+
+// checkSleeps fails the test unless the durations slept are want.
+func checkSleeps(t *testing.T, got *[]time.Duration, want ...time.Duration) {
+	t.Helper()
+	if fmt.Sprint(*got) != fmt.Sprint(want) {
+		t.Errorf("expected to sleep for %v, slept for %v", want, *got)
+	}
+}
+
+func TestWaitSucceeded(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{
+		Branch: "master",
+		Status: "not_running",
+		Steps: []circletest.Step{
+			{Name: "go test", Actions: []circletest.Action{{Status: "success", Runtime: 3 * time.Second}}},
+		},
+	})
+	s.Script("Shyp", "go-circle", b.BuildNum, "queued", "running", "running", "success")
+	if err := waitForBuild(context.Background(), newTestClient(s), "Shyp", "go-circle", "master", b.VCSRevision[:7]); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Build("Shyp", "go-circle", b.BuildNum); got.Status != "success" {
+		t.Errorf("expected build to be success, was %s", got.Status)
+	}
+	checkSleeps(t, sleeps, 10*time.Second, 10*time.Second, 10*time.Second, 10*time.Second)
+}
+
+func TestWaitFailed(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{
+		Branch: "master",
+		Status: "queued",
+		Steps: []circletest.Step{
+			{Name: "go test", Actions: []circletest.Action{{Status: "failed", Output: "--- FAIL: TestFoo"}}},
+		},
+		Tests: []circletest.TestResult{
+			{Name: "TestFoo", Classname: "github.com/Shyp/go-circle", Result: "failure", Message: "foo_test.go:10: bad foo"},
+		},
+	})
+	s.Script("Shyp", "go-circle", b.BuildNum, "running", "failed")
+	err := waitForBuild(context.Background(), newTestClient(s), "Shyp", "go-circle", "master", b.VCSRevision)
+	if err == nil {
+		t.Fatal("expected wait to return an error, got nil")
+	}
+	if !strings.Contains(err.Error(), "Build on master failed") {
+		t.Errorf("expected build failed error, got %v", err)
+	}
+	checkSleeps(t, sleeps, 10*time.Second, 10*time.Second)
+}
+
+func TestFormatFailedTests(t *testing.T) {
+	message := strings.Repeat("line\n", maxMessageLines+3)
+	out := formatFailedTests([]*circle.TestResult{
+		{Name: "TestFoo", Classname: "github.com/Shyp/go-circle", RunTime: 1.2345, Message: "foo_test.go:10: bad foo\n"},
+		{Name: "should render", RunTime: 0.5, Message: message},
+	})
+	want := "\n2 failed tests:\n\n" +
+		"github.com/Shyp/go-circle TestFoo (1.235s)\n" +
+		"    foo_test.go:10: bad foo\n" +
+		"should render (500ms)\n" +
+		strings.Repeat("    line\n", maxMessageLines) +
+		"    ... 3 more lines\n"
+	if out != want {
+		t.Errorf("formatFailedTests:\ngot:\n%s\nwant:\n%s", out, want)
+	}
+	out = formatFailedTests([]*circle.TestResult{{Name: "TestBar"}})
+	if want := "\n1 failed test:\n\nTestBar (0s)\n"; out != want {
+		t.Errorf("formatFailedTests: got %q, want %q", out, want)
+	}
+}
+
+func TestWaitTerminalStatuses(t *testing.T) {
+	for _, status := range []string{"canceled", "retried", "not_run", "some_new_status"} {
+		sleeps := stubSleep(t, nil)
+		s := circletest.NewServer()
+		b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "queued"})
+		s.Script("Shyp", "go-circle", b.BuildNum, "running", status)
+		done := make(chan error, 1)
+		go func() {
+			done <- waitForBuild(context.Background(), newTestClient(s), "Shyp", "go-circle", "master", b.VCSRevision)
+		}()
+		select {
+		case err := <-done:
+			if err == nil || !strings.Contains(err.Error(), "finished with status "+status) {
+				t.Errorf("%s: expected a finished with status error, got %v", status, err)
+			}
+			checkSleeps(t, sleeps, 10*time.Second, 10*time.Second)
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s: wait didn't return", status)
+		}
+		s.Close()
+	}
+}
+
+func TestWaitForTip(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "success"})
+	tip := "abcdef1234567"
+	sleeps := stubSleep(t, func(n int) {
+		if n == 2 {
+			s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", VCSRevision: tip, Status: "success"})
+		}
+	})
+	if err := waitForBuild(context.Background(), newTestClient(s), "Shyp", "go-circle", "master", tip); err != nil {
+		t.Fatal(err)
+	}
+	checkSleeps(t, sleeps, 5*time.Second, 5*time.Second)
+}
+
+func TestWaitCanceled(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "running"})
+	ctx, cancel := context.WithCancel(context.Background())
+	sleeps := stubSleep(t, func(n int) {
+		if n == 3 {
+			cancel()
+		}
+	})
+	err := waitForBuild(ctx, newTestClient(s), "Shyp", "go-circle", "master", b.VCSRevision)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	checkSleeps(t, sleeps, 10*time.Second, 10*time.Second, 10*time.Second)
+}
+
+func TestPollInterval(t *testing.T) {
+	tb := circle.TreeBuild{Previous: circle.PreviousBuild{Status: "success", BuildDurationMs: 10 * 60 * 1000}}
+	if d := pollInterval(tb.Previous, 30*time.Second); d != 5*time.Second {
+		t.Errorf("expected 5s in the first minute, got %v", d)
+	}
+	if d := pollInterval(tb.Previous, 2*time.Minute); d != 30*time.Second {
+		t.Errorf("expected 30s with 8 minutes left, got %v", d)
+	}
+	if d := pollInterval(tb.Previous, 595*time.Second); d != 3*time.Second {
+		t.Errorf("expected 3s near the end, got %v", d)
+	}
+}
+
+func TestWaitForSSH(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Status: "success"})
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Status: "running", Steps: []circletest.Step{
+		{Name: "Enable SSH", Actions: []circletest.Action{{Status: "running"}}},
+	}})
+	s.Script("Shyp", "go-circle", 2, "running", "success")
+	c := &circle.Client{BaseURL: s.URL, Tokens: circle.StaticToken("token")}
+	ctx := context.Background()
+	_, err := waitForSSH(ctx, c, "Shyp", "go-circle", 2)
+	if err == nil || !strings.Contains(err.Error(), "before SSH was enabled") {
+		t.Errorf("expected an error when the build finishes without SSH, got %v", err)
+	}
+
+	cb, err := c.RetryBuild(ctx, "github", "Shyp", "go-circle", 1, &circle.RebuildOptions{SSH: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands, err := waitForSSH(ctx, c, "Shyp", "go-circle", int(cb.BuildNum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 1 || commands[0] != "ssh -p 64535 127.0.0.1" {
+		t.Errorf("unexpected ssh commands %q", commands)
+	}
+	checkSleeps(t, sleeps, 5*time.Second, 5*time.Second)
+}
+
+// addWorkflow adds a build for each status to a workflow named name, and
+// scripts each build to run before it finishes with that status.
+func addWorkflow(s *circletest.Server, name string, statuses ...string) *circletest.Workflow {
+	wf := &circletest.Workflow{Name: name}
+	for i, status := range statuses {
+		b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "queued"})
+		s.Script("Shyp", "go-circle", b.BuildNum, "running", status)
+		wf.Jobs = append(wf.Jobs, &circletest.Job{Name: fmt.Sprintf("%s-%d", name, i), BuildNum: b.BuildNum})
+	}
+	return wf
+}
+
+func TestWaitForPipelineSucceeded(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	s.AddPipeline("Shyp", "go-circle", &circletest.Pipeline{
+		Branch:    "master",
+		Revision:  "4e8f3c1a9d",
+		Workflows: []*circletest.Workflow{addWorkflow(s, "build", "success", "success"), addWorkflow(s, "deploy", "success")},
+	})
+	err := waitForPipeline(context.Background(), newTestClient(s), "github", "Shyp", "go-circle", "master", "4e8f3c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if got, _ := s.Build("Shyp", "go-circle", i); got.Status != "success" {
+			t.Errorf("expected build %d to be success, was %s", i, got.Status)
+		}
+	}
+	checkSleeps(t, sleeps, time.Second, 5*time.Second, 5*time.Second)
+}
+
+func TestWaitForPipelineFailed(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	// The failed job isn't the latest build, so waiting for the latest
+	// build would report success.
+	s.AddPipeline("Shyp", "go-circle", &circletest.Pipeline{
+		Branch:    "master",
+		Revision:  "4e8f3c1a9d",
+		Workflows: []*circletest.Workflow{addWorkflow(s, "build", "failed"), addWorkflow(s, "lint", "success")},
+	})
+	err := waitForPipeline(context.Background(), newTestClient(s), "github", "Shyp", "go-circle", "master", "4e8f3c1a9d")
+	if err == nil || !strings.Contains(err.Error(), "Build on master failed! Failed workflows: build") {
+		t.Errorf("expected build workflow to fail, got %v", err)
+	}
+	checkSleeps(t, sleeps, time.Second, 5*time.Second, 5*time.Second)
+}
+
+func TestWaitForPipelineOnHold(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	wf := addWorkflow(s, "build", "success")
+	wf.Jobs = append(wf.Jobs, &circletest.Job{Name: "hold", Type: "approval", Dependencies: []string{"build-0"}})
+	s.AddPipeline("Shyp", "go-circle", &circletest.Pipeline{
+		Branch:    "master",
+		Revision:  "4e8f3c1a9d",
+		Workflows: []*circletest.Workflow{wf},
+	})
+	err := waitForPipeline(context.Background(), newTestClient(s), "github", "Shyp", "go-circle", "master", "4e8f3c1a9d")
+	if err == nil || !strings.Contains(err.Error(), "waiting for approval of build/hold") {
+		t.Errorf("expected an on hold error, got %v", err)
+	}
+	checkSleeps(t, sleeps, time.Second, 5*time.Second, 5*time.Second)
+}
+
+func TestWaitForPipelineWithoutPipelines(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "success"})
+	if err := waitForPipeline(context.Background(), newTestClient(s), "github", "Shyp", "go-circle", "master", b.VCSRevision); err != nil {
+		t.Fatal(err)
+	}
+	checkSleeps(t, sleeps, time.Second)
+}
+
+func TestWaitForPipelineNotFound(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "success"})
+	// The server only knows the project on GitHub, so it returns a 404 for
+	// the Bitbucket pipeline list.
+	if err := waitForPipeline(context.Background(), newTestClient(s), "bitbucket", "Shyp", "go-circle", "master", b.VCSRevision); err != nil {
+		t.Fatal(err)
+	}
+	checkSleeps(t, sleeps, time.Second)
+}
+
+func TestWaitForPipelineErrored(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	s.AddPipeline("Shyp", "go-circle", &circletest.Pipeline{
+		Revision: "4e8f3c1a9d",
+		State:    "errored",
+		Errors:   []string{"Config does not conform to schema"},
+	})
+	err := waitForPipeline(context.Background(), newTestClient(s), "github", "Shyp", "go-circle", "master", "4e8f3c1a9d")
+	if err == nil || !strings.Contains(err.Error(), "failed to start: Config does not conform to schema") {
+		t.Errorf("expected a pipeline error, got %v", err)
+	}
+	checkSleeps(t, sleeps, time.Second)
+}
+
+func TestWaitForBuildNum(t *testing.T) {
+	s := circletest.NewServer()
+	defer s.Close()
+	sleeps := stubSleep(t, nil)
+	b := s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", Status: "queued"})
+	s.Script("Shyp", "go-circle", b.BuildNum, "running", "failed")
+	// A newer build on the same commit that passed doesn't count.
+	s.AddBuild("Shyp", "go-circle", &circletest.Build{Branch: "master", VCSRevision: b.VCSRevision, Status: "success"})
+	err := waitForBuildNum(context.Background(), newTestClient(s), "Shyp", "go-circle", b.BuildNum)
+	if err == nil || !strings.Contains(err.Error(), "Build on master failed") {
+		t.Errorf("expected build failed error, got %v", err)
+	}
+	checkSleeps(t, sleeps, 10*time.Second, 10*time.Second)
+}
+
+func TestPipelineInterval(t *testing.T) {
+	tests := []struct {
+		expected, elapsed, want time.Duration
+	}{
+		{0, 10 * time.Second, 5 * time.Second},
+		{0, 2 * time.Minute, 12 * time.Second},
+		{0, 20 * time.Minute, 30 * time.Second},
+		{10 * time.Minute, 30 * time.Second, 5 * time.Second},
+		{10 * time.Minute, 2 * time.Minute, 30 * time.Second},
+		{10 * time.Minute, 595 * time.Second, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := pipelineInterval(tt.expected, tt.elapsed); got != tt.want {
+			t.Errorf("pipelineInterval(%v, %v): got %v, want %v", tt.expected, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestWorkflowsDuration(t *testing.T) {
+	start := time.Date(2018, 1, 2, 15, 0, 0, 0, time.UTC)
+	workflow := func(created, stopped time.Duration) *workflowJobs {
+		wf := &circlev2.Workflow{}
+		wf.CreatedAt.Time, wf.CreatedAt.Valid = start.Add(created), true
+		wf.StoppedAt.Time, wf.StoppedAt.Valid = start.Add(stopped), true
+		return &workflowJobs{workflow: wf}
+	}
+	// Workflows that run at the same time count once.
+	d := workflowsDuration([]*workflowJobs{workflow(0, 3*time.Minute), workflow(time.Minute, 5*time.Minute)})
+	if d != 5*time.Minute {
+		t.Errorf("expected 5m, got %v", d)
+	}
+}
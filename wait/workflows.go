@@ -0,0 +1,331 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Shyp/go-circle"
+	"github.com/Shyp/go-circle/circlev2"
+	"github.com/kevinburke/bigtext"
+)
+
+// WaitForPipeline polls CircleCI until every workflow in the most recent
+// pipeline for revision on branch completes, then prints statistics about each
+// job. vcsType is "github" or "bitbucket". WaitForPipeline returns an error
+// unless every workflow succeeded. If the project has no pipelines on branch,
+// or the v2 API can't find the project, WaitForPipeline waits for the latest
+// build on branch instead.
+func WaitForPipeline(ctx context.Context, vcsType, org, repoName, branch, revision string) error {
+	return waitForPipeline(ctx, circle.DefaultClient, vcsType, org, repoName, branch, revision)
+}
+
+// workflowJobs is a workflow along with its jobs.
+type workflowJobs struct {
+	workflow *circlev2.Workflow
+	jobs     []*circlev2.Job
+}
+
+func waitForPipeline(ctx context.Context, client *circle.Client, vcsType, org, repoName, branch, tip string) error {
+	fmt.Println("Waiting for latest build on", branch, "to complete")
+	// Give CircleCI a little bit of time to start
+	if err := sleep(ctx, 1*time.Second); err != nil {
+		return err
+	}
+	v2 := &circlev2.Client{Base: client}
+	pipeline, previous, err := findPipeline(ctx, v2, vcsType, org, repoName, branch, tip)
+	if err != nil {
+		return err
+	}
+	if pipeline == nil {
+		// The project doesn't use workflows, so there's a single build
+		// for each commit.
+		return waitForBuild(ctx, client, org, repoName, branch, tip)
+	}
+	expected := expectedDuration(ctx, v2, org, previous)
+	// The jobs in a finished workflow don't change, so they're only
+	// fetched once.
+	finished := make(map[string][]*circlev2.Job)
+	for {
+		// Until CircleCI has created the pipeline's workflows, it can
+		// still fail to start, for example if the configuration is
+		// invalid.
+		if pipeline.State != "created" {
+			p, err := v2.GetPipeline(ctx, org, pipeline.ID)
+			if err != nil {
+				if err := retryWait(ctx, err); err != nil {
+					return err
+				}
+				continue
+			}
+			pipeline = p
+		}
+		if pipeline.State == "errored" {
+			return reportPipelineError(repoName, branch, pipeline)
+		}
+		workflows, err := getWorkflowJobs(ctx, v2, org, pipeline.ID, finished)
+		if err != nil {
+			if err := retryWait(ctx, err); err != nil {
+				return err
+			}
+			continue
+		}
+		var elapsed time.Duration
+		if len(workflows) == 0 {
+			elapsed = roundDuration(time.Since(pipeline.CreatedAt.Time), time.Second)
+			fmt.Printf("Pipeline %d hasn't started any workflows yet (%s elapsed)\n", pipeline.Number, elapsed)
+		} else {
+			elapsed = roundDuration(workflowsDuration(workflows), time.Second)
+			if !workflowsRunning(workflows) {
+				return reportWorkflows(ctx, client, org, repoName, branch, workflows, elapsed)
+			}
+			fmt.Printf("Running: %s (%s elapsed)\n", describeWorkflows(workflows), elapsed)
+		}
+		if err := sleep(ctx, pipelineInterval(expected, elapsed)); err != nil {
+			return err
+		}
+	}
+}
+
+// findPipeline returns the most recent pipeline on branch for tip, waiting
+// for CircleCI to start it if it needs to, and the pipeline on branch before
+// it, if there is one on the same page. It returns nil if there aren't any
+// pipelines on the branch, or if the v2 API can't find the project or won't
+// let us see it.
+func findPipeline(ctx context.Context, v2 *circlev2.Client, vcsType, org, repoName, branch, tip string) (pipeline, previous *circlev2.Pipeline, err error) {
+	for {
+		pp, err := v2.ListPipelines(ctx, vcsType, org, repoName, &circlev2.PipelineOptions{Branch: branch})
+		if errors.Is(err, circle.ErrNotFound) || errors.Is(err, circle.ErrUnauthorized) {
+			return nil, nil, nil
+		}
+		if err != nil {
+			if err := retryWait(ctx, err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if len(pp.Items) == 0 {
+			return nil, nil, nil
+		}
+		for i, p := range pp.Items {
+			if p.MatchesRevision(tip) {
+				if i+1 < len(pp.Items) {
+					previous = pp.Items[i+1]
+				}
+				return p, previous, nil
+			}
+		}
+		latest := pp.Items[0].VCS.Revision
+		n := getMinTipLength(latest, tip)
+		fmt.Printf("Latest pipeline in Circle is for %s, waiting for %s...\n", latest[:n], tip[:n])
+		if err := sleep(ctx, 5*time.Second); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// expectedDuration returns how long the previous pipeline's workflows took to
+// run, or zero if there isn't a previous pipeline or it didn't succeed.
+func expectedDuration(ctx context.Context, v2 *circlev2.Client, org string, previous *circlev2.Pipeline) time.Duration {
+	if previous == nil {
+		return 0
+	}
+	workflows, err := v2.ListWorkflows(ctx, org, previous.ID)
+	if err != nil || len(workflows) == 0 {
+		return 0
+	}
+	wjs := make([]*workflowJobs, len(workflows))
+	for i, wf := range workflows {
+		if !wf.Status.IsSuccess() || !wf.StoppedAt.Valid {
+			return 0
+		}
+		wjs[i] = &workflowJobs{workflow: wf}
+	}
+	return workflowsDuration(wjs)
+}
+
+// pipelineInterval returns how long to wait before checking on a pipeline that
+// has been running for elapsed again. expected is how long the previous
+// pipeline took, or zero if that's unknown, in which case we check less often
+// the longer the pipeline runs.
+func pipelineInterval(expected, elapsed time.Duration) time.Duration {
+	if expected > 0 {
+		return remainingInterval(expected, elapsed)
+	}
+	d := elapsed / 10
+	if d < 5*time.Second {
+		return 5 * time.Second
+	}
+	if d > 30*time.Second {
+		return 30 * time.Second
+	}
+	return d
+}
+
+// workflowsDuration returns the time from the start of the first workflow to
+// the end of the last one. Workflows that haven't stopped are still going.
+func workflowsDuration(workflows []*workflowJobs) time.Duration {
+	var start, end time.Time
+	for _, wj := range workflows {
+		wf := wj.workflow
+		if wf.CreatedAt.Valid && (start.IsZero() || wf.CreatedAt.Time.Before(start)) {
+			start = wf.CreatedAt.Time
+		}
+		stopped := time.Now()
+		if wf.StoppedAt.Valid {
+			stopped = wf.StoppedAt.Time
+		}
+		if stopped.After(end) {
+			end = stopped
+		}
+	}
+	if start.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// getWorkflowJobs returns the workflows in a pipeline and their jobs. The jobs
+// of workflows that have finished are stored in finished, and not fetched
+// again.
+func getWorkflowJobs(ctx context.Context, v2 *circlev2.Client, org, pipelineID string, finished map[string][]*circlev2.Job) ([]*workflowJobs, error) {
+	workflows, err := v2.ListWorkflows(ctx, org, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*workflowJobs, len(workflows))
+	for i, wf := range workflows {
+		jobs, ok := finished[wf.ID]
+		if !ok {
+			jobs, err = v2.ListJobs(ctx, org, wf.ID)
+			if err != nil {
+				return nil, err
+			}
+			if wf.Status.IsTerminal() {
+				finished[wf.ID] = jobs
+			}
+		}
+		result[i] = &workflowJobs{workflow: wf, jobs: jobs}
+	}
+	return result, nil
+}
+
+// workflowsRunning reports whether any of the workflows are still running. A
+// workflow that is on hold is waiting for someone to approve a job, which
+// could take forever, so it doesn't count.
+func workflowsRunning(workflows []*workflowJobs) bool {
+	for _, wj := range workflows {
+		status := wj.workflow.Status
+		if !status.IsTerminal() && status != circlev2.WorkflowOnHold {
+			return true
+		}
+	}
+	return false
+}
+
+// describeWorkflows returns a summary of the progress of each workflow, like
+// "build running (2 of 3 jobs finished)".
+func describeWorkflows(workflows []*workflowJobs) string {
+	parts := make([]string, len(workflows))
+	for i, wj := range workflows {
+		finished := 0
+		for _, job := range wj.jobs {
+			if job.Status.IsTerminal() {
+				finished++
+			}
+		}
+		parts[i] = fmt.Sprintf("%s %s (%d of %d jobs finished)", wj.workflow.Name, wj.workflow.Status, finished, len(wj.jobs))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// heldJobs returns the names of the approval jobs that are on hold.
+func heldJobs(workflows []*workflowJobs) []string {
+	var names []string
+	for _, wj := range workflows {
+		for _, job := range wj.jobs {
+			if job.IsPendingApproval() {
+				names = append(names, wj.workflow.Name+"/"+job.Name)
+			}
+		}
+	}
+	return names
+}
+
+// reportPipelineError returns an error with the reasons CircleCI couldn't
+// start pipeline.
+func reportPipelineError(repoName, branch string, pipeline *circlev2.Pipeline) error {
+	msgs := make([]string, len(pipeline.Errors))
+	for i, perr := range pipeline.Errors {
+		msgs[i] = perr.Message
+	}
+	c := bigtext.Client{Name: fmt.Sprintf("%s (go-circle)", repoName)}
+	c.Display("build errored")
+	return fmt.Errorf("Pipeline %d on %s failed to start: %s", pipeline.Number, branch, strings.Join(msgs, "; "))
+}
+
+// reportWorkflows prints the statistics for every job that ran, and the
+// failures for every job that failed, and returns an error unless every
+// workflow succeeded.
+func reportWorkflows(ctx context.Context, client *circle.Client, org, repoName, branch string, workflows []*workflowJobs, duration time.Duration) error {
+	var failed, held, other []string
+	var otherStatus circlev2.WorkflowStatus
+	var buildURL, failedURL string
+	for _, wj := range workflows {
+		wf := wj.workflow
+		switch {
+		case wf.Status.IsSuccess():
+		case wf.Status.IsFailure():
+			failed = append(failed, wf.Name)
+		case wf.Status == circlev2.WorkflowOnHold:
+			held = append(held, wf.Name)
+		default:
+			other = append(other, fmt.Sprintf("%s %s", wf.Name, wf.Status))
+			otherStatus = wf.Status
+		}
+		for _, job := range wj.jobs {
+			if job.JobNumber == 0 {
+				continue
+			}
+			fmt.Printf("\n%s/%s (build %d) %s\n\n", wf.Name, job.Name, job.JobNumber, job.Status)
+			build, err := client.GetBuild(ctx, org, repoName, job.JobNumber)
+			if err != nil {
+				fmt.Printf("error getting build: %v\n", err)
+				continue
+			}
+			if buildURL == "" {
+				buildURL = build.BuildURL
+			}
+			fmt.Print(build.Statistics())
+			if !job.Status.IsFailure() {
+				continue
+			}
+			if failedURL == "" {
+				failedURL = build.BuildURL
+			}
+			printFailures(ctx, client, build)
+			fmt.Printf("\nURL: %s\n", build.BuildURL)
+		}
+	}
+	c := bigtext.Client{
+		Name:    fmt.Sprintf("%s (go-circle)", repoName),
+		OpenURL: buildURL,
+	}
+	switch {
+	case len(failed) > 0:
+		c.OpenURL = failedURL
+		c.Display("build failed")
+		return fmt.Errorf("Build on %s failed! Failed workflows: %s\n\n", branch, strings.Join(failed, ", "))
+	case len(held) > 0:
+		c.Display("build on hold")
+		return fmt.Errorf("Build on %s is waiting for approval of %s\n\n", branch, strings.Join(heldJobs(workflows), ", "))
+	case len(other) > 0:
+		c.Display("build " + string(otherStatus))
+		return fmt.Errorf("Build on %s finished with %s\n\n", branch, strings.Join(other, ", "))
+	}
+	fmt.Printf("\nAll %d workflows on %s succeeded. Tests took %s. Quitting.\n", len(workflows), branch, duration.String())
+	c.Display(branch + " build complete!")
+	return nil
+}